@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/hareku/evdev-trigger/pkg/config"
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"github.com/urfave/cli/v2"
+)
+
+var checkCommand = &cli.Command{
+	Name:  "check",
+	Usage: "Validate a configuration file.",
+	Flags: []cli.Flag{
+		&cli.StringFlag{
+			Name:     "config",
+			Usage:    "a configuration file path",
+			Aliases:  []string{"conf", "c"},
+			Required: true,
+		},
+		&cli.BoolFlag{
+			Name:  "devices",
+			Usage: "check the configured codes against the capabilities of connected devices",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		name := c.String("config")
+		w := c.App.Writer
+
+		conf, err := config.Read(name)
+		if err != nil {
+			var errs config.Errors
+			if !errors.As(err, &errs) {
+				fmt.Fprintf(w, "%s: %s\n", name, err)
+				return cli.Exit("", 1)
+			}
+			for _, e := range errs {
+				if e.Line == 0 {
+					fmt.Fprintf(w, "%s: %s\n", name, e.Msg)
+					continue
+				}
+				fmt.Fprintf(w, "%s:%d: %s\n", name, e.Line, e.Msg)
+			}
+			return cli.Exit("", 1)
+		}
+
+		if c.Bool("devices") {
+			if n := checkDevices(w, evdev.NewFinder(), conf); n > 0 {
+				return cli.Exit("", 1)
+			}
+		}
+		fmt.Fprintf(w, "%s: OK\n", name)
+		return nil
+	},
+}
+
+// checkDevices reports configured codes which the devices do not support,
+// and returns the number of problems.
+func checkDevices(w io.Writer, finder evdev.Finder, conf *config.Config) int {
+	n := 0
+	for _, dev := range conf.Devices {
+		d, err := finder.Find(dev.Match)
+		if err != nil {
+			fmt.Fprintf(w, "device %s: %s\n", dev.Label(), err)
+			n++
+			continue
+		}
+
+		for _, c := range configuredCodes(dev.Bindings) {
+			if !d.Supports(c) {
+				fmt.Fprintf(w, "device %s: %s is not supported by the device\n", dev.Label(), c)
+				n++
+			}
+		}
+		d.Close()
+	}
+	return n
+}
+
+// configuredCodes returns the codes of the triggers in b, without duplicates.
+func configuredCodes(b config.Bindings) []evdev.Code {
+	keys := make([]uint16, 0, len(b.Triggers)+len(b.ModeKeys))
+	for code := range b.Triggers {
+		keys = append(keys, code)
+	}
+	for _, k := range b.ModeKeys {
+		keys = append(keys, uint16(k.Key))
+	}
+	for _, c := range b.Chords {
+		for _, k := range c.Keys {
+			keys = append(keys, k.Codes...)
+		}
+	}
+	for _, c := range b.Sequences {
+		for _, k := range c.Keys {
+			keys = append(keys, k.Codes...)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	codes := make([]evdev.Code, 0, len(keys)+len(b.Relative)+len(b.Absolute)+len(b.Switches))
+	for _, code := range keys {
+		codes = append(codes, evdev.Code{Type: evdev.EV_KEY, Code: code})
+	}
+	for _, r := range b.Relative {
+		codes = append(codes, evdev.Code{Type: evdev.EV_REL, Code: uint16(r.Axis)})
+	}
+	for _, a := range b.Absolute {
+		codes = append(codes, evdev.Code{Type: evdev.EV_ABS, Code: uint16(a.Axis)})
+	}
+	for _, sw := range b.Switches {
+		codes = append(codes, evdev.Code{Type: evdev.EV_SW, Code: uint16(sw.Switch)})
+	}
+	if len(b.Gestures) > 0 {
+		// gestures are recognized from the multitouch slots of the type B protocol.
+		for _, code := range []uint16{evdev.ABS_MT_SLOT, evdev.ABS_MT_TRACKING_ID, evdev.ABS_MT_POSITION_X, evdev.ABS_MT_POSITION_Y} {
+			codes = append(codes, evdev.Code{Type: evdev.EV_ABS, Code: code})
+		}
+	}
+	for _, name := range b.ModeNames() {
+		codes = append(codes, configuredCodes(b.Modes[name].Bindings)...)
+	}
+
+	// a key is often used by several triggers, such as a chord and a mode, and is reported once.
+	seen := make(map[evdev.Code]bool, len(codes))
+	unique := codes[:0]
+	for _, c := range codes {
+		if !seen[c] {
+			seen[c] = true
+			unique = append(unique, c)
+		}
+	}
+	return unique
+}
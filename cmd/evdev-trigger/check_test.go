@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hareku/evdev-trigger/pkg/config"
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"github.com/hareku/evdev-trigger/pkg/evdev/evdevmock"
+	"github.com/stretchr/testify/require"
+)
+
+func readConfig(t *testing.T, body string) *config.Config {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(name, []byte(body), 0o600))
+	conf, err := config.Read(name)
+	require.NoError(t, err)
+	return conf
+}
+
+func Test_checkDevices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	conf := readConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "a"]
+chords:
+  - keys: [KEY_LEFTCTRL, KEY_A]
+    command: ["echo", "ctrl a"]
+sequences:
+  - keys: [KEY_A, KEY_C]
+    command: ["echo", "a c"]
+mode_keys:
+  - key: KEY_F13
+    mode: media
+modes:
+  media:
+    chords:
+      - keys: [KEY_LEFTSHIFT, KEY_B]
+        command: ["echo", "shift b"]
+    sequences:
+      - keys: [KEY_D, KEY_B]
+        command: ["echo", "d b"]
+`)
+	// the keys used by several triggers are checked once.
+	require.Equal(t, []evdev.Code{
+		{Type: evdev.EV_KEY, Code: 29},
+		{Type: evdev.EV_KEY, Code: 30},
+		{Type: evdev.EV_KEY, Code: 46},
+		{Type: evdev.EV_KEY, Code: 183},
+		{Type: evdev.EV_KEY, Code: 32},
+		{Type: evdev.EV_KEY, Code: 42},
+		{Type: evdev.EV_KEY, Code: 48},
+	}, configuredCodes(conf.Devices[0].Bindings))
+
+	device := evdevmock.NewMockDevice(ctrl)
+	device.EXPECT().Supports(gomock.Any()).AnyTimes().DoAndReturn(func(c evdev.Code) bool {
+		return c.Code == 30 || c.Code == 183
+	})
+	device.EXPECT().Close().Times(1).Return(nil)
+	finder := evdevmock.NewMockFinder(ctrl)
+	finder.EXPECT().Find(gomock.Any()).Times(1).Return(device, nil)
+
+	var b bytes.Buffer
+	require.Equal(t, 5, checkDevices(&b, finder, conf))
+	require.Equal(t, `device phys=a1:b2:c3: KEY_LEFTCTRL is not supported by the device
+device phys=a1:b2:c3: KEY_C is not supported by the device
+device phys=a1:b2:c3: KEY_D is not supported by the device
+device phys=a1:b2:c3: KEY_LEFTSHIFT is not supported by the device
+device phys=a1:b2:c3: KEY_B is not supported by the device
+`, b.String())
+}
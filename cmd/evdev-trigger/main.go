@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
+	"os/signal"
 	"sync"
+	"syscall"
 
 	"github.com/hareku/evdev-trigger/pkg/config"
 	"github.com/hareku/evdev-trigger/pkg/evdev"
@@ -18,11 +21,15 @@ func main() {
 		Name:  "evdev-trigger",
 		Usage: "Trigger commands by evdev events.",
 		Flags: []cli.Flag{
+			// config is not required at the app level so that subcommands have their own.
 			&cli.StringFlag{
-				Name:     "config",
-				Usage:    "a configuration file path",
-				Aliases:  []string{"conf", "c"},
-				Required: true,
+				Name:    "config",
+				Usage:   "a configuration file path",
+				Aliases: []string{"conf", "c"},
+			},
+			&cli.BoolFlag{
+				Name:  "watch-config",
+				Usage: "reload the configuration file when it changes",
 			},
 			&cli.BoolFlag{
 				Name:    "debug",
@@ -30,9 +37,17 @@ func main() {
 				Aliases: []string{"d"},
 			},
 		},
+		Commands: []*cli.Command{
+			checkCommand,
+		},
 		Action: func(c *cli.Context) error {
 			ctx := c.Context
 			logger := watch.NewLogger(os.Stdout, c.Bool("debug"))
+			if c.String("config") == "" {
+				err := errors.New(`required flag "config" not set`)
+				logger.Errorf("%s", err)
+				return err
+			}
 
 			conf, err := config.Read(c.String("config"))
 			if err != nil {
@@ -40,26 +55,56 @@ func main() {
 				return err
 			}
 
+			// SIGINT and SIGTERM stop watching devices and the running commands.
+			sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
 			cnd := sync.NewCond(new(sync.Mutex))
-			eg, ctx := errgroup.WithContext(ctx)
+			eg, ctx := errgroup.WithContext(sigCtx)
 			eg.Go(func() error {
-				return notify.NewFsNotifier().Subscribe(ctx, cnd)
+				return notify.NewFsNotifier(notify.NewFsNotifierInput{}).Subscribe(ctx, cnd)
+			})
+
+			runner := watch.NewRunner(conf.Concurrency)
+			group := watch.NewGroup(watch.NewGroupInput{
+				Devices: conf.Devices,
+				Logger:  logger,
+				Finder:  evdev.NewFinder(),
+				Executor: watch.NewExecutor(watch.NewExecutorInput{
+					GracePeriod: conf.GracePeriod,
+				}),
+				Runner:        runner,
+				ReconnectCond: cnd,
 			})
 			eg.Go(func() error {
-				return watch.NewWatcher(watch.NewWatcherInput{
-					Phys:   conf.Phys,
-					Logger: logger,
-					Finder: evdev.NewFinder(),
-					Handler: watch.NewHandler(watch.NewHandlerInput{
-						Logger:   logger,
-						Executor: watch.NewExecutor(),
-						Triggers: conf.Triggers,
-					}),
-					ReconnectCond: cnd,
-				}).Run(ctx)
+				return group.Run(ctx)
 			})
 
-			return eg.Wait()
+			r := newReloader(c.String("config"), logger, group)
+			eg.Go(func() error {
+				return r.Run(ctx)
+			})
+			eg.Go(func() error {
+				return r.WatchSignal(ctx)
+			})
+			if c.Bool("watch-config") {
+				fileCnd := sync.NewCond(new(sync.Mutex))
+				eg.Go(func() error {
+					return notify.NewFileNotifier(c.String("config")).Subscribe(ctx, fileCnd)
+				})
+				eg.Go(func() error {
+					return r.WatchCond(ctx, fileCnd)
+				})
+			}
+
+			err = eg.Wait()
+			// commands run outside of the errgroup, and are stopped by the cancellation of ctx.
+			runner.Wait()
+			if sigCtx.Err() != nil {
+				logger.Infof("Stopped by signal")
+				return nil
+			}
+			return err
 		},
 	}
 
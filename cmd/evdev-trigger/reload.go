@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hareku/evdev-trigger/pkg/config"
+	"github.com/hareku/evdev-trigger/pkg/watch"
+)
+
+// reloadDelay is the time to wait for successive writes to the config file settle.
+const reloadDelay = 200 * time.Millisecond
+
+// reloader reads the config file on requests and applies it to the group.
+// If the new config is invalid, the group keeps running with the current one.
+type reloader struct {
+	name   string
+	logger watch.Logger
+	group  watch.Group
+	reqCh  chan struct{}
+}
+
+func newReloader(name string, logger watch.Logger, group watch.Group) *reloader {
+	return &reloader{
+		name:   name,
+		logger: logger,
+		group:  group,
+		reqCh:  make(chan struct{}, 1),
+	}
+}
+
+func (r *reloader) request() {
+	select {
+	case r.reqCh <- struct{}{}:
+	default:
+	}
+}
+
+func (r *reloader) Run(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.reqCh:
+		}
+		if err := r.settle(ctx); err != nil {
+			return err
+		}
+
+		conf, err := config.Read(r.name)
+		if err != nil {
+			r.logger.Errorf("Reloading config %q failed, keep running with the current config: %s", r.name, err)
+			continue
+		}
+		r.group.Apply(conf.Devices)
+		r.logger.Infof("Reloaded config %q", r.name)
+	}
+}
+
+// settle waits until no request comes for reloadDelay.
+func (r *reloader) settle(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-r.reqCh:
+		case <-time.After(reloadDelay):
+			return nil
+		}
+	}
+}
+
+// WatchSignal requests reloading on SIGHUP.
+func (r *reloader) WatchSignal(ctx context.Context) error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-sigCh:
+			r.logger.Infof("Got SIGHUP, reloading config %q", r.name)
+			r.request()
+		}
+	}
+}
+
+// WatchCond requests reloading on every broadcast to cnd.
+func (r *reloader) WatchCond(ctx context.Context, cnd *sync.Cond) error {
+	go func() {
+		<-ctx.Done()
+		cnd.L.Lock()
+		cnd.Broadcast()
+		cnd.L.Unlock()
+	}()
+
+	cnd.L.Lock()
+	defer cnd.L.Unlock()
+	// ctx is checked under the lock before waiting, since the broadcast on cancellation may come first.
+	for ctx.Err() == nil {
+		cnd.Wait()
+		if ctx.Err() == nil {
+			r.request()
+		}
+	}
+	return ctx.Err()
+}
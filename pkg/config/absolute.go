@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"gopkg.in/yaml.v3"
+)
+
+// AbsCode is an EV_ABS code.
+// In YAML, it is written as a number or a name such as ABS_Z.
+type AbsCode uint16
+
+func (c *AbsCode) UnmarshalYAML(value *yaml.Node) error {
+	code, err := parseCode(value.Value, evdev.EV_ABS)
+	if err != nil {
+		return typeErrorf(value, "%s", err)
+	}
+	*c = AbsCode(code)
+	return nil
+}
+
+func (c AbsCode) String() string {
+	return evdev.CodeName(evdev.EV_ABS, uint16(c))
+}
+
+// Crossing is how the value of an absolute axis fires a trigger.
+type Crossing string
+
+const (
+	// CrossingRising fires when the value rises to the threshold or above.
+	CrossingRising Crossing = "rising"
+	// CrossingFalling fires when the value falls to the threshold or below.
+	CrossingFalling Crossing = "falling"
+	// CrossingRange fires when the value enters the range from min to max.
+	CrossingRange Crossing = "range"
+)
+
+// AbsoluteConfig is a trigger fired by the value of an absolute axis, such as a pedal or a slider.
+// After firing, the trigger fires again only after the value goes out beyond the hysteresis,
+// so that a noisy value around the threshold does not fire it repeatedly.
+type AbsoluteConfig struct {
+	Axis AbsCode  `yaml:"axis"`
+	On   Crossing `yaml:"on"`
+	// Threshold is the value of rising and falling.
+	Threshold float64 `yaml:"threshold"`
+	// Min and Max are the range of range.
+	Min float64 `yaml:"min"`
+	Max float64 `yaml:"max"`
+	// Hysteresis is the distance the value must go back beyond the threshold or the range to fire again.
+	Hysteresis float64 `yaml:"hysteresis"`
+	// Normalize scales values into 0 to 1 by the minimum and maximum of the axis reported by the device.
+	Normalize bool `yaml:"normalize"`
+
+	CommandConfig CommandConfig `yaml:",inline"`
+}
+
+func (c *AbsoluteConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain AbsoluteConfig
+	c.CommandConfig.setNode(value)
+	return keepTypeError(value.Decode((*plain)(c)), &c.CommandConfig.errs)
+}
+
+// Name returns the axis and the crossing of the trigger.
+func (c AbsoluteConfig) Name() string {
+	if c.On == CrossingRange {
+		return fmt.Sprintf("%s %s %g-%g", c.Axis, c.On, c.Min, c.Max)
+	}
+	return fmt.Sprintf("%s %s %g", c.Axis, c.On, c.Threshold)
+}
+
+// Inside reports whether v fires the trigger.
+func (c AbsoluteConfig) Inside(v float64) bool {
+	switch c.On {
+	case CrossingRising:
+		return v >= c.Threshold
+	case CrossingFalling:
+		return v <= c.Threshold
+	case CrossingRange:
+		return c.Min <= v && v <= c.Max
+	}
+	return false
+}
+
+// Outside reports whether v is beyond the hysteresis, so that the trigger can fire again.
+func (c AbsoluteConfig) Outside(v float64) bool {
+	switch c.On {
+	case CrossingRising:
+		return v < c.Threshold-c.Hysteresis
+	case CrossingFalling:
+		return v > c.Threshold+c.Hysteresis
+	case CrossingRange:
+		return v < c.Min-c.Hysteresis || v > c.Max+c.Hysteresis
+	}
+	return false
+}
+
+func (c AbsoluteConfig) validate(global ProcessConfig) Errors {
+	name := fmt.Sprintf("absolute %s", c.Name())
+	errs := c.CommandConfig.validate(name, global, false)
+	switch c.On {
+	case CrossingRising, CrossingFalling:
+	case CrossingRange:
+		if c.Min > c.Max {
+			errs.add(c.CommandConfig.line, "%s: min must not be greater than max", name)
+		}
+	default:
+		errs.add(c.CommandConfig.line, "absolute %s: unknown on %q", c.Axis, c.On)
+	}
+	if c.Hysteresis < 0 {
+		errs.add(c.CommandConfig.line, "%s: hysteresis must not be negative", name)
+	}
+	return errs
+}
@@ -0,0 +1,565 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"gopkg.in/yaml.v3"
+)
+
+// Bindings is the set of triggers of a device.
+type Bindings struct {
+	Triggers  Triggers         `yaml:"triggers"`
+	Chords    []ChordConfig    `yaml:"chords"`
+	Sequences []SequenceConfig `yaml:"sequences"`
+	Relative  []RelativeConfig `yaml:"relative"`
+	Absolute  []AbsoluteConfig `yaml:"absolute"`
+	Switches  []SwitchConfig   `yaml:"switches"`
+	Gestures  []GestureConfig  `yaml:"gestures"`
+	// Modes is the triggers of modes, which are active in addition to the triggers above.
+	Modes    map[string]ModeConfig `yaml:"modes"`
+	ModeKeys []ModeKeyConfig       `yaml:"mode_keys"`
+}
+
+// eachCommand calls f with the commands of all triggers in b, including the triggers of modes.
+func (b Bindings) eachCommand(f func(c *CommandConfig)) {
+	for _, ts := range b.Triggers {
+		for i := range ts {
+			f(&ts[i].CommandConfig)
+		}
+	}
+	for i := range b.Chords {
+		f(&b.Chords[i].CommandConfig)
+	}
+	for i := range b.Sequences {
+		f(&b.Sequences[i].CommandConfig)
+	}
+	for i := range b.Relative {
+		f(&b.Relative[i].CommandConfig)
+	}
+	for i := range b.Absolute {
+		f(&b.Absolute[i].CommandConfig)
+	}
+	for i := range b.Switches {
+		f(&b.Switches[i].CommandConfig)
+	}
+	for i := range b.Gestures {
+		f(&b.Gestures[i].CommandConfig)
+	}
+	for _, m := range b.Modes {
+		m.eachCommand(f)
+	}
+}
+
+// IsZero reports whether b has no triggers.
+func (b Bindings) IsZero() bool {
+	return len(b.Triggers) == 0 && len(b.Chords) == 0 && len(b.Sequences) == 0 && len(b.Relative) == 0 && len(b.Absolute) == 0 && len(b.Switches) == 0 && len(b.Gestures) == 0 &&
+		len(b.Modes) == 0 && len(b.ModeKeys) == 0
+}
+
+// Triggers maps EV_KEY codes to their triggers.
+// In YAML, codes are written as numbers or names such as KEY_VOLUMEUP.
+type Triggers map[uint16]KeyTriggers
+
+func (t *Triggers) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.MappingNode {
+		return typeErrorf(value, "triggers must be a mapping")
+	}
+
+	*t = make(Triggers, len(value.Content)/2)
+	names := make(map[uint16]string, len(value.Content)/2)
+	var errs []string
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		k, v := value.Content[i], value.Content[i+1]
+		code, err := ParseKeyCode(k.Value)
+		if err != nil {
+			errs = append(errs, lineError(k, err.Error()))
+			continue
+		}
+		if prev, ok := names[code]; ok {
+			errs = append(errs, lineError(k, fmt.Sprintf("triggers %q and %q have the same code %d", prev, k.Value, code)))
+			continue
+		}
+		names[code] = k.Value
+
+		var kt KeyTriggers
+		if err := v.Decode(&kt); err != nil {
+			return err
+		}
+		// a key without triggers, such as "KEY_A:" or "KEY_A: []", is never validated by its triggers.
+		if len(kt) == 0 {
+			errs = append(errs, lineError(k, fmt.Sprintf("%s: command is empty", evdev.CodeName(evdev.EV_KEY, code))))
+			continue
+		}
+		(*t)[code] = kt
+	}
+	if len(errs) > 0 {
+		return &yaml.TypeError{Errors: errs}
+	}
+	return nil
+}
+
+// ParseKeyCode parses s as a number or a name of EV_KEY code, such as KEY_VOLUMEUP or BTN_LEFT.
+func ParseKeyCode(s string) (uint16, error) {
+	return parseCode(s, evdev.EV_KEY)
+}
+
+// parseCode parses s as a number or a name of a code of the event type typ.
+func parseCode(s string, typ uint16) (uint16, error) {
+	if n, err := strconv.ParseUint(s, 0, 16); err == nil {
+		return uint16(n), nil
+	}
+	c, ok := evdev.LookupCode(s)
+	if !ok {
+		return 0, fmt.Errorf("unknown event code %q", s)
+	}
+	if c.Type != typ {
+		return 0, fmt.Errorf("%s is a code of %s, not %s", s, evdev.TypeName(c.Type), evdev.TypeName(typ))
+	}
+	return c.Code, nil
+}
+
+// KeyTriggers is the triggers of a key.
+// In YAML, a single trigger can be written as a mapping instead of a sequence.
+type KeyTriggers []KeyTrigger
+
+func (t *KeyTriggers) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.MappingNode {
+		var kt KeyTrigger
+		if err := value.Decode(&kt); err != nil {
+			return err
+		}
+		*t = KeyTriggers{kt}
+		return nil
+	}
+	type plain KeyTriggers
+	return value.Decode((*plain)(t))
+}
+
+// Threshold returns the threshold of the i-th trigger.
+// If it is not configured, a short press uses the smallest threshold of the long presses of the key
+// so that every press fires one of them, and others use DefaultPressThreshold.
+func (t KeyTriggers) Threshold(i int) time.Duration {
+	if t[i].Threshold > 0 {
+		return t[i].Threshold
+	}
+	if !t[i].OnOrDefault().Has(EdgeShortPress) {
+		return DefaultPressThreshold
+	}
+	var min time.Duration
+	for j, kt := range t {
+		if !kt.OnOrDefault().Has(EdgeLongPress) {
+			continue
+		}
+		if th := t.Threshold(j); min == 0 || th < min {
+			min = th
+		}
+	}
+	if min == 0 {
+		return DefaultPressThreshold
+	}
+	return min
+}
+
+// MaxTaps returns the largest number of taps of the triggers.
+func (t KeyTriggers) MaxTaps() int {
+	max := 1
+	for _, kt := range t {
+		if n := kt.TapsOrDefault(); n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+// TapWindow returns the longest tap window of the triggers with multiple taps,
+// or DefaultTapWindow if none is configured.
+func (t KeyTriggers) TapWindow() time.Duration {
+	var max time.Duration
+	for _, kt := range t {
+		if kt.TapsOrDefault() > 1 && kt.TapWindow > max {
+			max = kt.TapWindow
+		}
+	}
+	if max == 0 {
+		return DefaultTapWindow
+	}
+	return max
+}
+
+// Edge is an event of a key which fires a trigger.
+type Edge string
+
+const (
+	// EdgePress fires on the press of a key.
+	EdgePress Edge = "press"
+	// EdgeRelease fires on the release of a key.
+	EdgeRelease Edge = "release"
+	// EdgeRepeat fires on every autorepeat of a held key.
+	EdgeRepeat Edge = "repeat"
+	// EdgeShortPress fires on the release of a key held shorter than the threshold.
+	EdgeShortPress Edge = "short_press"
+	// EdgeLongPress fires on the release of a key held for the threshold or longer.
+	// Only the one with the longest threshold fires if a key has some long presses.
+	EdgeLongPress Edge = "long_press"
+)
+
+// Edges is the edges which fire a trigger.
+// In YAML, a single edge can be written as a string instead of a sequence.
+type Edges []Edge
+
+func (e *Edges) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*e = Edges{Edge(value.Value)}
+		return nil
+	}
+	type plain Edges
+	return value.Decode((*plain)(e))
+}
+
+// Has reports whether e contains edge.
+func (e Edges) Has(edge Edge) bool {
+	for _, v := range e {
+		if v == edge {
+			return true
+		}
+	}
+	return false
+}
+
+func (e Edges) String() string {
+	s := make([]string, len(e))
+	for i, v := range e {
+		s[i] = string(v)
+	}
+	return strings.Join(s, ",")
+}
+
+// DefaultPressThreshold is the threshold between short and long presses if it is not configured.
+const DefaultPressThreshold = 500 * time.Millisecond
+
+// DefaultTapWindow is the tap window of a key if it is not configured.
+const DefaultTapWindow = 300 * time.Millisecond
+
+// KeyTrigger is a trigger of a key.
+type KeyTrigger struct {
+	// On is the edges which fire the trigger, release if empty.
+	On Edges `yaml:"on"`
+	// Threshold is the duration which separates short and long presses.
+	Threshold time.Duration `yaml:"threshold"`
+	// WhileHeld fires a long press once the threshold passes while the key is still held,
+	// instead of on the release.
+	WhileHeld bool `yaml:"while_held"`
+	// Taps is the number of taps which fire the trigger, where a tap is a release which is not a long press.
+	// The triggers of fewer taps on the same key wait for the tap window to pass.
+	Taps int `yaml:"taps"`
+	// TapWindow is the maximum time from a release to the next press of the taps.
+	TapWindow time.Duration `yaml:"tap_window"`
+
+	CommandConfig CommandConfig `yaml:",inline"`
+}
+
+func (t *KeyTrigger) UnmarshalYAML(value *yaml.Node) error {
+	type plain KeyTrigger
+	t.CommandConfig.setNode(value)
+	return keepTypeError(value.Decode((*plain)(t)), &t.CommandConfig.errs)
+}
+
+// Name returns the name of the trigger of code.
+func (t KeyTrigger) Name(code uint16) string {
+	name := evdev.CodeName(evdev.EV_KEY, code)
+	if on := t.OnOrDefault(); !(len(on) == 1 && on[0] == EdgeRelease) {
+		name = fmt.Sprintf("%s %s", name, on)
+	}
+	if n := t.TapsOrDefault(); n > 1 {
+		name = fmt.Sprintf("%s %d taps", name, n)
+	}
+	return name
+}
+
+// OnOrDefault returns On, or release if it is not configured.
+func (t KeyTrigger) OnOrDefault() Edges {
+	if len(t.On) == 0 {
+		return Edges{EdgeRelease}
+	}
+	return t.On
+}
+
+// TapsOrDefault returns Taps, or 1 if it is not configured.
+func (t KeyTrigger) TapsOrDefault() int {
+	if t.Taps > 0 {
+		return t.Taps
+	}
+	return 1
+}
+
+// CommandConfig is the command of a trigger and the options to run it.
+type CommandConfig struct {
+	// Command is the arguments of the command, or a command line run by the shell if it is written as a string.
+	Command Command
+	// Shell runs Command written as a string with -c, which overrides the global shell.
+	Shell    string        `yaml:"shell"`
+	Interval time.Duration `yaml:"interval"`
+	// Repeat runs the command again while the key which fired it is held.
+	Repeat *RepeatConfig `yaml:"repeat"`
+	// Concurrency is the maximum number of the commands of the trigger which run at once.
+	Concurrency int `yaml:"concurrency"`
+	// Overlap is what to do when the trigger fires while its commands reach Concurrency.
+	Overlap Overlap `yaml:"overlap"`
+	// Timeout stops the command if it runs longer, unlimited if zero.
+	Timeout time.Duration `yaml:"command_timeout"`
+	// Stdin is what the command reads from its stdin, nothing if empty.
+	Stdin         Stdin `yaml:"stdin"`
+	ProcessConfig `yaml:",inline"`
+
+	line int
+	errs Errors
+	// script is true if Command is written as a string.
+	script bool
+	// mode is the mode of the trigger, empty for the base bindings.
+	mode string
+}
+
+func (c *CommandConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain CommandConfig
+	c.setNode(value)
+	return keepTypeError(value.Decode((*plain)(c)), &c.errs)
+}
+
+// setNode records the position and the form of the command in value, the mapping of a trigger.
+// Triggers call it since UnmarshalYAML of inline fields is not called.
+func (c *CommandConfig) setNode(value *yaml.Node) {
+	c.line = value.Line
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		if value.Content[i].Value == "command" {
+			c.script = value.Content[i+1].Kind == yaml.ScalarNode
+		}
+	}
+}
+
+// Mode returns the mode which the trigger belongs to, or an empty string for the base bindings.
+// It is set by Bindings.ForMode.
+func (c CommandConfig) Mode() string {
+	return c.mode
+}
+
+// IsScript reports whether Command is written as a string, which runs by the shell.
+func (c CommandConfig) IsScript() bool {
+	return c.script
+}
+
+// applyShell converts Command written as a string into the arguments of the shell.
+// shell is the global shell, which is used unless Shell is set.
+func (c *CommandConfig) applyShell(shell string) {
+	if !c.script || len(c.Command) != 1 {
+		return
+	}
+	if c.Shell != "" {
+		shell = c.Shell
+	}
+	if shell == "" {
+		shell = DefaultShell
+	}
+	c.Command = Command{shell, "-c", c.Command[0]}
+}
+
+// ConcurrencyOrDefault returns the concurrency of the trigger, or 0 if it is unlimited.
+// Unless Concurrency is set, it is 1 if Overlap is set other than parallel.
+func (c CommandConfig) ConcurrencyOrDefault() int {
+	if c.Concurrency > 0 || c.Overlap == "" || c.Overlap == OverlapParallel {
+		return c.Concurrency
+	}
+	return 1
+}
+
+// OverlapOrDefault returns the overlap policy of the trigger,
+// which defaults to queue with Concurrency and parallel without it.
+func (c CommandConfig) OverlapOrDefault() Overlap {
+	switch {
+	case c.Overlap != "":
+		return c.Overlap
+	case c.Concurrency > 0:
+		return OverlapQueue
+	default:
+		return OverlapParallel
+	}
+}
+
+// Overlap is the policy of a trigger fired while its previous commands are still running.
+type Overlap string
+
+const (
+	// OverlapQueue runs the command after a running one finishes.
+	OverlapQueue Overlap = "queue"
+	// OverlapDrop does not run the command.
+	OverlapDrop Overlap = "drop"
+	// OverlapRestart stops the oldest running command and runs the command.
+	OverlapRestart Overlap = "restart"
+	// OverlapParallel runs the command together with the running ones.
+	OverlapParallel Overlap = "parallel"
+)
+
+// Stdin is the input of a command.
+type Stdin string
+
+// StdinEventJSON writes a JSON document of the event, the device, the key state and the trigger to the command.
+const StdinEventJSON Stdin = "event-json"
+
+// DefaultShell is the default shell of commands written as strings.
+const DefaultShell = "/bin/sh"
+
+// Command is the arguments of a command.
+// In YAML, it is written as a sequence of arguments, or a string run by the shell.
+type Command []string
+
+func (c *Command) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if value.Value != "" {
+			*c = Command{value.Value}
+		}
+		return nil
+	}
+	var args []string
+	if err := value.Decode(&args); err != nil {
+		return err
+	}
+	*c = args
+	return nil
+}
+
+// RepeatConfig is the repeat mode of a command, independent of the autorepeat of the kernel.
+type RepeatConfig struct {
+	// Delay is the time from the first run to the first repeat, Period if zero.
+	Delay time.Duration `yaml:"delay"`
+	// Period is the time between repeats.
+	Period time.Duration `yaml:"period"`
+	// Max is the maximum number of repeats, unlimited if zero.
+	Max int `yaml:"max"`
+}
+
+// DelayOrDefault returns Delay, or Period if it is not configured.
+func (r RepeatConfig) DelayOrDefault() time.Duration {
+	if r.Delay > 0 {
+		return r.Delay
+	}
+	return r.Period
+}
+
+// Strictness is how a chord treats keys pressed in addition to its keys.
+type Strictness string
+
+const (
+	// StrictnessExact fires the chord only if no other keys are pressed.
+	StrictnessExact Strictness = "exact"
+	// StrictnessSuperset fires the chord even if other keys are pressed.
+	StrictnessSuperset Strictness = "superset"
+)
+
+// ChordConfig is a trigger fired when all keys are pressed together.
+// It fires on the press of the key which completes the chord.
+type ChordConfig struct {
+	Keys       Keys       `yaml:"keys"`
+	Strictness Strictness `yaml:"strictness"`
+
+	// CommandConfig is not embedded, or its UnmarshalYAML would be promoted.
+	CommandConfig CommandConfig `yaml:",inline"`
+}
+
+func (c *ChordConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain ChordConfig
+	c.CommandConfig.setNode(value)
+	return keepTypeError(value.Decode((*plain)(c)), &c.CommandConfig.errs)
+}
+
+// Name returns the keys of the chord joined by "+".
+func (c ChordConfig) Name() string {
+	return c.Keys.Name("+")
+}
+
+// Has reports whether code is one of the codes of k.
+func (k Key) Has(code uint16) bool {
+	for _, c := range k.Codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Keys is the keys of a chord or a sequence.
+type Keys []Key
+
+// Name returns the names of keys joined by sep.
+func (k Keys) Name(sep string) string {
+	s := make([]string, len(k))
+	for i, key := range k {
+		s[i] = key.Name
+	}
+	return strings.Join(s, sep)
+}
+
+// Key is a key of a chord or a sequence, which is satisfied by any of Codes.
+type Key struct {
+	Name  string
+	Codes []uint16
+}
+
+// modifierAliases are names of modifier keys which match both left and right keys.
+var modifierAliases = map[string][]string{
+	"KEY_CTRL":  {"KEY_LEFTCTRL", "KEY_RIGHTCTRL"},
+	"KEY_SHIFT": {"KEY_LEFTSHIFT", "KEY_RIGHTSHIFT"},
+	"KEY_ALT":   {"KEY_LEFTALT", "KEY_RIGHTALT"},
+	"KEY_META":  {"KEY_LEFTMETA", "KEY_RIGHTMETA"},
+}
+
+func (k *Key) UnmarshalYAML(value *yaml.Node) error {
+	k.Name = value.Value
+	if aliases, ok := modifierAliases[value.Value]; ok {
+		for _, a := range aliases {
+			code, _ := ParseKeyCode(a)
+			k.Codes = append(k.Codes, code)
+		}
+		return nil
+	}
+
+	code, err := ParseKeyCode(value.Value)
+	if err != nil {
+		return typeErrorf(value, "%s", err)
+	}
+	k.Codes = []uint16{code}
+	return nil
+}
+
+// DefaultSequenceTimeout is the timeout of a sequence if it is not configured.
+const DefaultSequenceTimeout = time.Second
+
+// SequenceConfig is a trigger fired when keys are pressed in order within the timeout.
+type SequenceConfig struct {
+	Keys Keys `yaml:"keys"`
+	// Timeout is the maximum time from the first press to the last press.
+	Timeout time.Duration `yaml:"timeout"`
+
+	CommandConfig CommandConfig `yaml:",inline"`
+}
+
+func (c *SequenceConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain SequenceConfig
+	c.CommandConfig.setNode(value)
+	return keepTypeError(value.Decode((*plain)(c)), &c.CommandConfig.errs)
+}
+
+// Name returns the keys of the sequence joined by spaces.
+func (c SequenceConfig) Name() string {
+	return c.Keys.Name(" ")
+}
+
+// TimeoutOrDefault returns Timeout, or DefaultSequenceTimeout if it is not configured.
+func (c SequenceConfig) TimeoutOrDefault() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return DefaultSequenceTimeout
+}
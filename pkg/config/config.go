@@ -1,33 +1,145 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"time"
 
-	"gopkg.in/yaml.v2"
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Phys     string                   `yaml:"phys"`
-	Triggers map[uint16]CommandConfig `yaml:"triggers"`
+	// Phys and Bindings are the single device form of configuration,
+	// they are converted into Devices by Read.
+	Phys     string `yaml:"phys"`
+	Bindings `yaml:",inline"`
+
+	Devices []DeviceConfig `yaml:"devices"`
+	// Concurrency is the maximum number of commands which run at once over all devices.
+	Concurrency int `yaml:"concurrency"`
+	// GracePeriod is the time from SIGTERM to SIGKILL for stopping commands.
+	GracePeriod time.Duration `yaml:"grace_period"`
+	// Shell runs commands written as strings, DefaultShell if empty.
+	Shell string `yaml:"shell"`
+	// ProcessConfig is the default process attributes of all commands.
+	ProcessConfig `yaml:",inline"`
+
+	// line is the line of the top level, which is the line of the device in the single device form.
+	line int
+	// lines is the lines of the keys at the top level.
+	lines map[string]int
+}
+
+func (c *Config) UnmarshalYAML(value *yaml.Node) error {
+	type plain Config
+	c.line = value.Line
+	c.lines = keyLines(value)
+	return value.Decode((*plain)(c))
 }
 
-type CommandConfig struct {
-	Command  Command
-	Interval time.Duration `yaml:"interval"`
+// keyLine returns the line of key at the top level, or 0 if it is not written.
+func (c *Config) keyLine(key string) int {
+	return c.lines[key]
 }
 
-type Command []string
+type DeviceConfig struct {
+	// Name is an optional label of the device, used in logs.
+	Name string `yaml:"name"`
+	// Phys is a shorthand of Match.Phys.
+	Phys     string        `yaml:"phys"`
+	Match    evdev.Matcher `yaml:"match"`
+	Bindings `yaml:",inline"`
+
+	line int
+	errs Errors
+}
+
+func (d *DeviceConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain DeviceConfig
+	d.line = value.Line
+	return keepTypeError(value.Decode((*plain)(d)), &d.errs)
+}
+
+// Label returns the name of the device, or its matcher if the name is empty.
+func (d DeviceConfig) Label() string {
+	if d.Name != "" {
+		return d.Name
+	}
+	return d.Match.String()
+}
 
+// Read reads and validates the configuration file.
+// If the file has problems, it returns Errors which reports all of them.
 func Read(name string) (*Config, error) {
 	b, err := os.ReadFile(name)
 	if err != nil {
 		return nil, fmt.Errorf("reading file failed: %w", err)
 	}
-	var c *Config
-	if err := yaml.Unmarshal(b, &c); err != nil {
+	var root yaml.Node
+	if err := yaml.Unmarshal(b, &root); err != nil {
 		return nil, fmt.Errorf("unmarshal yaml failed: %w", err)
 	}
-	return c, nil
+	if len(root.Content) == 0 {
+		return nil, errors.New("config is empty")
+	}
+
+	errs := unknownFields(&root, reflect.TypeOf(Config{}))
+	var c Config
+	if err := root.Decode(&c); err != nil {
+		var te *yaml.TypeError
+		if !errors.As(err, &te) {
+			return nil, fmt.Errorf("unmarshal yaml failed: %w", err)
+		}
+		errs = append(errs, parseTypeError(te)...)
+	}
+	errs = append(errs, c.normalize()...)
+	errs = append(errs, c.validate()...)
+	if len(errs) > 0 {
+		errs.sort()
+		return nil, errs
+	}
+	// the process attributes at the top level are applied after validation, so that their problems are reported once.
+	for _, d := range c.Devices {
+		d.Bindings.eachCommand(func(cmd *CommandConfig) {
+			cmd.ProcessConfig.inherit(c.ProcessConfig)
+		})
+	}
+	return &c, nil
+}
+
+// normalize moves the single device form into Devices, the phys shorthand into the matcher of each device,
+// and commands written as strings into the arguments of the shell.
+func (c *Config) normalize() Errors {
+	var errs Errors
+	if c.Phys != "" || !c.Bindings.IsZero() {
+		if len(c.Devices) > 0 {
+			errs.add(c.keyLine("devices"), "phys and triggers cannot be used together with devices")
+			return errs
+		}
+		c.Devices = []DeviceConfig{{
+			Phys:     c.Phys,
+			Bindings: c.Bindings,
+			line:     c.line,
+		}}
+		c.Phys = ""
+		c.Bindings = Bindings{}
+	}
+
+	for i := range c.Devices {
+		d := &c.Devices[i]
+		if d.Phys != "" {
+			if d.Match.Phys != "" {
+				errs.add(d.line, "phys cannot be used together with match.phys")
+			}
+			d.Match.Phys = d.Phys
+			d.Phys = ""
+		}
+		d.Bindings.eachCommand(func(cmd *CommandConfig) {
+			cmd.applyShell(c.Shell)
+		})
+	}
+	return errs
 }
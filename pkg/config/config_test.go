@@ -0,0 +1,1051 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hareku/evdev-trigger/pkg/config"
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(name, []byte(body), 0o600))
+	return name
+}
+
+func TestRead_SingleDevice(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  115:
+    command: ["echo", "Hello"]
+`))
+	require.NoError(t, err)
+	require.Len(t, conf.Devices, 1)
+	require.Equal(t, evdev.Matcher{Phys: "a1:b2:c3"}, conf.Devices[0].Match)
+	require.Equal(t, config.Command{"echo", "Hello"}, conf.Devices[0].Triggers[115][0].CommandConfig.Command)
+}
+
+func TestRead_Devices(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+devices:
+  - name: keyboard
+    phys: a1:b2:c3
+    triggers:
+      115:
+        command: ["echo", "Hello"]
+  - phys: d4:e5:f6
+    triggers:
+      30:
+        command: ["echo", "World"]
+`))
+	require.NoError(t, err)
+	require.Len(t, conf.Devices, 2)
+	require.Equal(t, "keyboard", conf.Devices[0].Label())
+	require.Equal(t, "phys=d4:e5:f6", conf.Devices[1].Label())
+	require.Equal(t, config.Command{"echo", "World"}, conf.Devices[1].Triggers[30][0].CommandConfig.Command)
+}
+
+func TestRead_MixedForms(t *testing.T) {
+	_, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+devices:
+  - phys: d4:e5:f6
+`))
+	require.Error(t, err)
+}
+
+func TestRead_Match(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+devices:
+  - match:
+      name_regex: "^Logitech .* Receiver$"
+      vendor: 0x046d
+      product: 0xc52b
+    triggers:
+      115:
+        command: ["echo", "Hello"]
+  - match:
+      path: /dev/input/by-id/usb-foot-pedal-event-kbd
+`))
+	require.NoError(t, err)
+	require.Equal(t, evdev.Matcher{
+		NameRegex: "^Logitech .* Receiver$",
+		Vendor:    0x046d,
+		Product:   0xc52b,
+	}, conf.Devices[0].Match)
+	require.Equal(t, "/dev/input/by-id/usb-foot-pedal-event-kbd", conf.Devices[1].Match.Path)
+}
+
+func TestRead_InvalidMatch(t *testing.T) {
+	for name, body := range map[string]string{
+		"empty": `
+devices:
+  - name: keyboard
+`,
+		"regex": `
+devices:
+  - match:
+      name_regex: "("
+`,
+		"phys twice": `
+devices:
+  - phys: a1:b2:c3
+    match:
+      phys: d4:e5:f6
+`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := config.Read(writeConfig(t, body))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRead_KeyNames(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_VOLUMEUP:
+    command: ["echo", "up"]
+  BTN_LEFT:
+    command: ["echo", "left"]
+  0x72:
+    command: ["echo", "down"]
+`))
+	require.NoError(t, err)
+	triggers := conf.Devices[0].Triggers
+	require.Equal(t, config.Command{"echo", "up"}, triggers[115][0].CommandConfig.Command)
+	require.Equal(t, config.Command{"echo", "left"}, triggers[0x110][0].CommandConfig.Command)
+	require.Equal(t, config.Command{"echo", "down"}, triggers[114][0].CommandConfig.Command)
+}
+
+func TestRead_InvalidKeyNames(t *testing.T) {
+	for name, body := range map[string]string{
+		"unknown": `
+phys: a1:b2:c3
+triggers:
+  KEY_NOTHING:
+    command: ["echo"]
+`,
+		"not a key": `
+phys: a1:b2:c3
+triggers:
+  REL_WHEEL:
+    command: ["echo"]
+`,
+		"duplicated": `
+phys: a1:b2:c3
+triggers:
+  115:
+    command: ["echo"]
+  KEY_VOLUMEUP:
+    command: ["echo"]
+`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := config.Read(writeConfig(t, body))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestRead_Validation(t *testing.T) {
+	_, err := config.Read(writeConfig(t, `
+devices:
+  - name: keyboard
+    phys: a1:b2:c3
+    triggers:
+      KEY_A:
+        command: []
+      KEY_B:
+        command: ["evdev-trigger-nonexistent-command"]
+        interval: -1s
+      KEY_C:
+        command: ["echo"]
+        intreval: 1s
+      KEY_NOTHING:
+        command: ["echo"]
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+
+	lines := make([]int, len(errs))
+	for i, e := range errs {
+		lines[i] = e.Line
+	}
+	require.Equal(t, []int{7, 9, 9, 13, 14}, lines, err.Error())
+}
+
+func TestRead_EmptyKeyTriggers(t *testing.T) {
+	_, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+  KEY_B: []
+  KEY_C:
+    command: ["echo"]
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 2, err.Error())
+	require.Equal(t, 4, errs[0].Line)
+	require.Equal(t, 5, errs[1].Line)
+	require.Contains(t, errs[0].Error(), "KEY_A: command is empty")
+	require.Contains(t, errs[1].Error(), "KEY_B: command is empty")
+}
+
+func TestRead_TopLevelLines(t *testing.T) {
+	_, err := config.Read(writeConfig(t, `
+concurrency: -1
+grace_period: -1s
+dir: relative
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo"]
+devices:
+  - phys: d4:e5:f6
+    triggers:
+      KEY_B:
+        command: ["echo"]
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	lines := make([]int, len(errs))
+	for i, e := range errs {
+		lines[i] = e.Line
+	}
+	require.Equal(t, []int{2, 3, 4, 9, 10}, lines, err.Error())
+
+	// the device of the single device form is at the top level.
+	_, err = config.Read(writeConfig(t, `
+triggers:
+  KEY_A:
+    command: ["echo"]
+`))
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 1, err.Error())
+	require.Equal(t, 2, errs[0].Line)
+}
+
+func TestRead_Chords(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+chords:
+  - keys: [KEY_CTRL, KEY_LEFTALT, KEY_T]
+    strictness: superset
+    command: ["echo", "terminal"]
+`))
+	require.NoError(t, err)
+	chords := conf.Devices[0].Chords
+	require.Len(t, chords, 1)
+	require.Equal(t, config.Keys{
+		{Name: "KEY_CTRL", Codes: []uint16{29, 97}},
+		{Name: "KEY_LEFTALT", Codes: []uint16{56}},
+		{Name: "KEY_T", Codes: []uint16{20}},
+	}, chords[0].Keys)
+	require.Equal(t, config.StrictnessSuperset, chords[0].Strictness)
+	require.Equal(t, config.Command{"echo", "terminal"}, chords[0].CommandConfig.Command)
+	require.Equal(t, "KEY_CTRL+KEY_LEFTALT+KEY_T", chords[0].Name())
+}
+
+func TestRead_InvalidChords(t *testing.T) {
+	_, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+chords:
+  - keys: [KEY_CTRL, KEY_NOTHING]
+    strictness: loose
+    command: ["echo", "terminal"]
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 2, err.Error())
+}
+
+func TestRead_Sequences(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+sequences:
+  - keys: [KEY_A, KEY_B, KEY_C]
+    timeout: 2s
+    command: ["echo", "abc"]
+  - keys: [KEY_SPACE, KEY_T]
+    command: ["echo", "terminal"]
+`))
+	require.NoError(t, err)
+	seqs := conf.Devices[0].Sequences
+	require.Len(t, seqs, 2)
+	require.Equal(t, "KEY_A KEY_B KEY_C", seqs[0].Name())
+	require.Equal(t, time.Second*2, seqs[0].TimeoutOrDefault())
+	require.Equal(t, config.DefaultSequenceTimeout, seqs[1].TimeoutOrDefault())
+	require.Equal(t, config.Command{"echo", "terminal"}, seqs[1].CommandConfig.Command)
+}
+
+func TestRead_Presses(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_POWER:
+    - on: short_press
+      command: ["echo", "suspend"]
+    - on: long_press
+      threshold: 3s
+      while_held: true
+      command: ["echo", "poweroff"]
+  KEY_A:
+    on: long_press
+    command: ["echo", "A"]
+`))
+	require.NoError(t, err)
+	power := conf.Devices[0].Triggers[116]
+	require.Len(t, power, 2)
+	require.Equal(t, config.Edges{config.EdgeShortPress}, power[0].On)
+	require.Equal(t, time.Second*3, power.Threshold(0), "a short press defaults to the threshold of the long press")
+	require.True(t, power[1].WhileHeld)
+	require.Equal(t, "KEY_POWER long_press", power[1].Name(116))
+
+	keyA := conf.Devices[0].Triggers[30]
+	require.Len(t, keyA, 1)
+	require.Equal(t, config.DefaultPressThreshold, keyA.Threshold(0))
+}
+
+func TestRead_InvalidPresses(t *testing.T) {
+	_, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_POWER:
+    - on: tap
+      command: ["echo", "suspend"]
+    - on: short_press
+      while_held: true
+      threshold: -1s
+      command: ["echo", "poweroff"]
+    - on: long_press
+      hold: 3s
+      command: ["echo", "poweroff"]
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+
+	lines := make([]int, len(errs))
+	for i, e := range errs {
+		lines[i] = e.Line
+	}
+	require.Equal(t, []int{5, 7, 7, 12}, lines, err.Error())
+}
+
+func TestRead_Taps(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    - command: ["echo", "1"]
+    - taps: 2
+      tap_window: 400ms
+      command: ["echo", "2"]
+    - taps: 3
+      command: ["echo", "3"]
+`))
+	require.NoError(t, err)
+	keyA := conf.Devices[0].Triggers[30]
+	require.Equal(t, 3, keyA.MaxTaps())
+	require.Equal(t, time.Millisecond*400, keyA.TapWindow())
+	require.Equal(t, "KEY_A 2 taps", keyA[1].Name(30))
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    on: long_press
+    taps: 2
+    command: ["echo", "2"]
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 1, err.Error())
+}
+
+func TestRead_Edges(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "release"]
+  KEY_B:
+    on: press
+    command: ["echo", "press"]
+  KEY_VOLUMEUP:
+    on: [press, repeat]
+    command: ["echo", "up"]
+`))
+	require.NoError(t, err)
+	triggers := conf.Devices[0].Triggers
+	require.Equal(t, config.Edges{config.EdgeRelease}, triggers[30][0].OnOrDefault())
+	require.Equal(t, "KEY_A", triggers[30][0].Name(30))
+	require.Equal(t, config.Edges{config.EdgePress}, triggers[48][0].On)
+	require.Equal(t, config.Edges{config.EdgePress, config.EdgeRepeat}, triggers[115][0].On)
+	require.Equal(t, "KEY_VOLUMEUP press,repeat", triggers[115][0].Name(115))
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    on: [press, long_press]
+    command: ["echo", "A"]
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 1, err.Error())
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    on: [press, release, press]
+    command: ["echo", "A"]
+`))
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 1, err.Error())
+	require.Equal(t, 5, errs[0].Line)
+	require.Contains(t, errs[0].Msg, "on press is duplicated")
+}
+
+func TestRead_Repeat(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_VOLUMEUP:
+    on: press
+    command: ["echo", "up"]
+    repeat:
+      delay: 400ms
+      period: 100ms
+      max: 20
+  KEY_VOLUMEDOWN:
+    on: press
+    command: ["echo", "down"]
+    repeat:
+      period: 100ms
+`))
+	require.NoError(t, err)
+	triggers := conf.Devices[0].Triggers
+	require.Equal(t, &config.RepeatConfig{Delay: time.Millisecond * 400, Period: time.Millisecond * 100, Max: 20}, triggers[115][0].CommandConfig.Repeat)
+	require.Equal(t, time.Millisecond*100, triggers[114][0].CommandConfig.Repeat.DelayOrDefault())
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_VOLUMEUP:
+    command: ["echo", "up"]
+    repeat:
+      delay: 400ms
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 2, err.Error())
+
+	// the triggers without a key to hold cannot repeat.
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+relative:
+  - axis: REL_WHEEL
+    command: ["echo", "wheel"]
+    repeat:
+      period: 100ms
+absolute:
+  - axis: ABS_Z
+    on: rising
+    threshold: 0.5
+    command: ["echo", "z"]
+    repeat:
+      period: 100ms
+switches:
+  - switch: SW_LID
+    state: on
+    command: ["echo", "lid"]
+    repeat:
+      period: 100ms
+gestures:
+  - gesture: tap
+    command: ["echo", "tap"]
+    repeat:
+      period: 100ms
+`))
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 4, err.Error())
+	for _, e := range errs {
+		require.Contains(t, e.Error(), "repeat can be used only with keys, chords and sequences")
+	}
+}
+
+func TestRead_Relative(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+relative:
+  - axis: REL_WHEEL
+    direction: positive
+    command: ["echo", "up"]
+  - axis: 0x07
+    threshold: 3
+    command: ["echo", "dial"]
+`))
+	require.NoError(t, err)
+	rel := conf.Devices[0].Relative
+	require.Len(t, rel, 2)
+	require.Equal(t, config.RelCode(0x08), rel[0].Axis)
+	require.Equal(t, "REL_WHEEL positive", rel[0].Name())
+	require.Equal(t, int32(1), rel[0].ThresholdOrDefault())
+	require.Equal(t, "REL_DIAL", rel[1].Name())
+	require.Equal(t, int32(3), rel[1].ThresholdOrDefault())
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+relative:
+  - axis: KEY_A
+    direction: up
+    command: ["echo", "up"]
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 2, err.Error())
+}
+
+func TestRead_Absolute(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+absolute:
+  - axis: ABS_Z
+    on: rising
+    threshold: 0.5
+    hysteresis: 0.1
+    normalize: true
+    command: ["echo", "pressed"]
+  - axis: ABS_X
+    on: range
+    min: 0
+    max: 0.33
+    normalize: true
+    command: ["echo", "left"]
+`))
+	require.NoError(t, err)
+	abs := conf.Devices[0].Absolute
+	require.Len(t, abs, 2)
+	require.Equal(t, config.AbsCode(0x02), abs[0].Axis)
+	require.Equal(t, "ABS_Z rising 0.5", abs[0].Name())
+	require.True(t, abs[0].Inside(0.5))
+	require.False(t, abs[0].Outside(0.45))
+	require.True(t, abs[0].Outside(0.3))
+	require.Equal(t, "ABS_X range 0-0.33", abs[1].Name())
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+absolute:
+  - axis: ABS_X
+    on: range
+    min: 1
+    max: 0
+    command: ["echo", "left"]
+  - axis: ABS_Y
+    on: crossing
+    command: ["echo", "up"]
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 2, err.Error())
+}
+
+func TestRead_Switches(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+switches:
+  - switch: SW_LID
+    state: on
+    command: ["echo", "closed"]
+  - switch: SW_HEADPHONE_INSERT
+    state: off
+    initial: true
+    command: ["echo", "unplugged"]
+`))
+	require.NoError(t, err)
+	sw := conf.Devices[0].Switches
+	require.Len(t, sw, 2)
+	require.Equal(t, "SW_LID on", sw[0].Name())
+	require.True(t, sw[0].Fires(true))
+	require.Equal(t, config.SwCode(0x02), sw[1].Switch)
+	require.True(t, sw[1].Fires(false))
+	require.True(t, sw[1].Initial)
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+switches:
+  - switch: SW_LID
+    state: closed
+    command: ["echo", "closed"]
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 1, err.Error())
+}
+
+func TestRead_Gestures(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+gestures:
+  - gesture: swipe
+    fingers: 3
+    direction: left
+    command: ["echo", "back"]
+  - gesture: pinch
+    direction: in
+    distance: 0.3
+    command: ["echo", "zoom out"]
+  - gesture: tap
+    fingers: 2
+    command: ["echo", "menu"]
+`))
+	require.NoError(t, err)
+	g := conf.Devices[0].Gestures
+	require.Len(t, g, 3)
+	require.Equal(t, "swipe 3 left", g[0].Name())
+	require.Equal(t, config.DefaultSwipeDistance, g[0].DistanceOrDefault())
+	require.Equal(t, time.Duration(0), g[0].TimeoutOrDefault())
+	require.Equal(t, 2, g[1].FingersOrDefault())
+	require.Equal(t, 0.3, g[1].DistanceOrDefault())
+	require.Equal(t, "tap 2", g[2].Name())
+	require.Equal(t, config.DefaultTapTimeout, g[2].TimeoutOrDefault())
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+gestures:
+  - gesture: swipe
+    direction: in
+    command: ["echo", "back"]
+  - gesture: pinch
+    fingers: 1
+    direction: out
+    command: ["echo", "zoom in"]
+  - gesture: flick
+    command: ["echo", "flick"]
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 3, err.Error())
+	require.Equal(t, 4, errs[0].Line)
+	require.Equal(t, 7, errs[1].Line)
+	require.Equal(t, 11, errs[2].Line)
+}
+
+func TestRead_Modes(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "a"]
+  KEY_B:
+    command: ["echo", "b"]
+mode_keys:
+  - key: KEY_F13
+    mode: media
+    action: momentary
+  - key: KEY_F14
+    mode: media
+modes:
+  media:
+    triggers:
+      KEY_A:
+        command: ["echo", "play"]
+    mode_keys:
+      - key: KEY_F14
+        mode: default
+        action: one_shot
+`))
+	require.NoError(t, err)
+	b := conf.Devices[0].Bindings
+	require.Len(t, b.Modes, 1)
+	require.Equal(t, config.ModeMomentary, b.ModeKeys[0].ActionOrDefault())
+	require.Equal(t, config.ModeToggle, b.ModeKeys[1].ActionOrDefault())
+	require.True(t, b.HasMode(config.ModeDefault))
+	require.False(t, b.HasMode("editing"))
+
+	media := b.ForMode("media")
+	require.Equal(t, config.Command{"echo", "play"}, media.Triggers[30][0].CommandConfig.Command)
+	require.Equal(t, config.Command{"echo", "b"}, media.Triggers[48][0].CommandConfig.Command)
+	require.Len(t, media.ModeKeys, 2)
+	require.Equal(t, config.KeyCode(183), media.ModeKeys[0].Key)
+	require.Equal(t, config.ModeDefault, media.ModeKeys[1].Mode)
+	require.Empty(t, media.Modes)
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_F13:
+    command: ["echo", "a"]
+mode_keys:
+  - key: KEY_F13
+    mode: editing
+    action: hold
+modes:
+  media:
+    modes:
+      inner:
+        triggers:
+          KEY_A:
+            command: ["echo", "a"]
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 4, err.Error())
+	require.Equal(t, 7, errs[0].Line)
+	require.Equal(t, 12, errs[3].Line)
+}
+
+func TestRead_Concurrency(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+concurrency: 4
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "a"]
+    concurrency: 1
+`))
+	require.NoError(t, err)
+	require.Equal(t, 4, conf.Concurrency)
+	require.Equal(t, 1, conf.Devices[0].Triggers[30][0].CommandConfig.Concurrency)
+
+	_, err = config.Read(writeConfig(t, `
+concurrency: -1
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "a"]
+    concurrency: -1
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 2, err.Error())
+}
+
+func TestRead_Overlap(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "a"]
+    overlap: restart
+  KEY_B:
+    command: ["echo", "b"]
+    concurrency: 2
+  KEY_C:
+    command: ["echo", "c"]
+`))
+	require.NoError(t, err)
+	ts := conf.Devices[0].Triggers
+	require.Equal(t, config.OverlapRestart, ts[30][0].CommandConfig.OverlapOrDefault())
+	require.Equal(t, 1, ts[30][0].CommandConfig.ConcurrencyOrDefault())
+	require.Equal(t, config.OverlapQueue, ts[48][0].CommandConfig.OverlapOrDefault())
+	require.Equal(t, 2, ts[48][0].CommandConfig.ConcurrencyOrDefault())
+	require.Equal(t, config.OverlapParallel, ts[46][0].CommandConfig.OverlapOrDefault())
+	require.Equal(t, 0, ts[46][0].CommandConfig.ConcurrencyOrDefault())
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "a"]
+    overlap: skip
+  KEY_B:
+    command: ["echo", "b"]
+    overlap: parallel
+    concurrency: 2
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 2, err.Error())
+}
+
+func TestRead_Timeout(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+grace_period: 1s
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "a"]
+    command_timeout: 10s
+sequences:
+  - keys: [KEY_A, KEY_B]
+    timeout: 2s
+    command_timeout: 5s
+    command: ["echo", "ab"]
+gestures:
+  - gesture: tap
+    timeout: 300ms
+    command: ["echo", "tap"]
+`))
+	require.NoError(t, err)
+	require.Equal(t, time.Second, conf.GracePeriod)
+	d := conf.Devices[0]
+	require.Equal(t, 10*time.Second, d.Triggers[30][0].CommandConfig.Timeout)
+	// timeout of sequences and gestures is not the timeout of their commands.
+	require.Equal(t, 2*time.Second, d.Sequences[0].Timeout)
+	require.Equal(t, 5*time.Second, d.Sequences[0].CommandConfig.Timeout)
+	require.Equal(t, 300*time.Millisecond, d.Gestures[0].Timeout)
+	require.Zero(t, d.Gestures[0].CommandConfig.Timeout)
+
+	_, err = config.Read(writeConfig(t, `
+grace_period: -1s
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "a"]
+    command_timeout: -1s
+  KEY_B:
+    command: ["echo", "b"]
+    timeout: 1s
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 3, err.Error())
+	require.Contains(t, err.Error(), "field timeout not found")
+}
+
+func TestRead_Shell(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+shell: /bin/sh
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: echo a | cat
+  KEY_B:
+    command: echo b
+    shell: sh
+  KEY_C:
+    command: ["echo", "c | cat"]
+modes:
+  media:
+    triggers:
+      KEY_A:
+        command: echo play
+`))
+	require.NoError(t, err)
+	d := conf.Devices[0]
+	require.Equal(t, config.Command{"/bin/sh", "-c", "echo a | cat"}, d.Triggers[30][0].CommandConfig.Command)
+	require.Equal(t, config.Command{"sh", "-c", "echo b"}, d.Triggers[48][0].CommandConfig.Command)
+	require.Equal(t, config.Command{"echo", "c | cat"}, d.Triggers[46][0].CommandConfig.Command)
+	require.Equal(t, config.Command{"/bin/sh", "-c", "echo play"}, d.Modes["media"].Triggers[30][0].CommandConfig.Command)
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ""
+  KEY_B:
+    command: ["echo", "b"]
+    shell: bash
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 2, err.Error())
+}
+
+func TestRead_Placeholders(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "pressed {{.CodeName}} value {{.Value}}"]
+  KEY_B:
+    command: awk 'BEGIN { print "{{" }'
+relative:
+  - axis: REL_WHEEL
+    command: ["echo", "scrolled {{.Delta}}"]
+`))
+	require.NoError(t, err)
+	d := conf.Devices[0]
+	cmd, err := d.Triggers[30][0].CommandConfig.Command.Render(config.CommandData{CodeName: "KEY_A", Value: 1})
+	require.NoError(t, err)
+	require.Equal(t, config.Command{"echo", "pressed KEY_A value 1"}, cmd)
+	cmd, err = d.Relative[0].CommandConfig.Command.Render(config.CommandData{Delta: -2})
+	require.NoError(t, err)
+	require.Equal(t, config.Command{"echo", "scrolled -2"}, cmd)
+	// the braces which are not placeholders are kept in commands written as strings.
+	require.Equal(t, config.Command{"/bin/sh", "-c", `awk 'BEGIN { print "{{" }'`}, d.Triggers[48][0].CommandConfig.Command)
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "{{.CodeNam}}"]
+  KEY_B:
+    command: ["echo", "{{.Value"]
+  KEY_C:
+    command: notify-send {{.DeviceName}}
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 3, err.Error())
+	require.Contains(t, err.Error(), "CodeNam")
+}
+
+func TestRead_Stdin(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["cat"]
+    stdin: event-json
+`))
+	require.NoError(t, err)
+	require.Equal(t, config.StdinEventJSON, conf.Devices[0].Triggers[30][0].CommandConfig.Stdin)
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["cat"]
+    stdin: json
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 1, err.Error())
+}
+
+func TestRead_Process(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+dir: /
+env:
+  set:
+    A: global
+    B: global
+  unset: [SECRET]
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "a"]
+  KEY_B:
+    command: ["/bin/echo", "b"]
+    dir: /tmp
+    user: root
+    group: "0"
+    env:
+      inherit: false
+      set:
+        B: trigger
+`))
+	require.NoError(t, err)
+	d := conf.Devices[0]
+	a := d.Triggers[30][0].CommandConfig.ProcessConfig
+	require.Equal(t, "/", a.Dir)
+	require.True(t, a.Env.InheritOrDefault())
+	require.Equal(t, map[string]string{"A": "global", "B": "global"}, a.Env.Set)
+	require.Equal(t, []string{"SECRET"}, a.Env.Unset)
+	b := d.Triggers[48][0].CommandConfig.ProcessConfig
+	require.Equal(t, "/tmp", b.Dir)
+	require.Equal(t, "root", b.User)
+	require.Equal(t, "0", b.Group)
+	require.False(t, b.Env.InheritOrDefault())
+	require.Equal(t, map[string]string{"A": "global", "B": "trigger"}, b.Env.Set)
+
+	// the problems of the top level options are reported once.
+	_, err = config.Read(writeConfig(t, `
+user: no-such-user-of-evdev-trigger
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "a"]
+    dir: tmp
+  KEY_B:
+    command: ["echo", "b"]
+    group: no-such-group-of-evdev-trigger
+    env:
+      set:
+        "A=B": c
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 4, err.Error())
+}
+
+func TestRead_ProcessCommandPath(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "bin"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh\n"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bin", "handle"), []byte("#!/bin/sh\n"), 0o755))
+
+	// the commands are found in the directory and PATH of their own.
+	_, err := config.Read(writeConfig(t, `
+dir: `+dir+`
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["./run.sh"]
+  KEY_B:
+    command: ["handle"]
+    env:
+      set:
+        PATH: `+filepath.Join(dir, "bin")+`
+  KEY_C:
+    command: ["bin/handle"]
+`))
+	require.NoError(t, err)
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["./run.sh"]
+  KEY_B:
+    command: ["handle"]
+    dir: `+dir+`
+  KEY_C:
+    command: ["echo", "c"]
+    env:
+      unset: [PATH]
+  KEY_D:
+    command: ["echo", "d"]
+    env:
+      inherit: false
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 4, err.Error())
+}
+
+func TestRead_Session(t *testing.T) {
+	conf, err := config.Read(writeConfig(t, `
+session: active-user
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "a"]
+  KEY_B:
+    command: ["echo", "b"]
+    user: root
+`))
+	require.NoError(t, err)
+	d := conf.Devices[0]
+	require.Equal(t, config.SessionActiveUser, d.Triggers[30][0].CommandConfig.Session)
+	// the user of a trigger overrides the session at the top level.
+	require.Equal(t, config.Session(""), d.Triggers[48][0].CommandConfig.Session)
+	require.Equal(t, "root", d.Triggers[48][0].CommandConfig.User)
+
+	_, err = config.Read(writeConfig(t, `
+phys: a1:b2:c3
+triggers:
+  KEY_A:
+    command: ["echo", "a"]
+    session: active-user
+    user: root
+  KEY_B:
+    command: ["echo", "b"]
+    session: current
+`))
+	var errs config.Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 2, err.Error())
+}
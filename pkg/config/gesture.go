@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GestureKind is a kind of multitouch gestures.
+type GestureKind string
+
+const (
+	GestureSwipe GestureKind = "swipe"
+	GesturePinch GestureKind = "pinch"
+	GestureTap   GestureKind = "tap"
+)
+
+// GestureDirection is the direction of a swipe or a pinch.
+type GestureDirection string
+
+const (
+	GestureUp    GestureDirection = "up"
+	GestureDown  GestureDirection = "down"
+	GestureLeft  GestureDirection = "left"
+	GestureRight GestureDirection = "right"
+	GestureIn    GestureDirection = "in"
+	GestureOut   GestureDirection = "out"
+)
+
+const (
+	// DefaultSwipeDistance is the default minimum distance of swipes.
+	DefaultSwipeDistance = 0.2
+	// DefaultPinchDistance is the default minimum distance of pinches.
+	DefaultPinchDistance = 0.1
+	// DefaultTapDistance is the default maximum distance of fingers moved in taps.
+	DefaultTapDistance = 0.03
+	// DefaultTapTimeout is the default maximum duration of taps.
+	DefaultTapTimeout = 250 * time.Millisecond
+)
+
+// GestureConfig is a trigger fired by a gesture on a multitouch device, such as a touchpad or a touchscreen.
+// The gesture is recognized when all fingers are lifted.
+type GestureConfig struct {
+	Gesture GestureKind `yaml:"gesture"`
+	// Fingers is the number of fingers of the gesture, which defaults to 2 for pinch and 1 for the others.
+	Fingers int `yaml:"fingers"`
+	// Direction is up, down, left or right for swipe, and in or out for pinch.
+	Direction GestureDirection `yaml:"direction"`
+	// Distance is the minimum distance for swipe and pinch, or the maximum distance for tap,
+	// in fractions of the width and height of the device.
+	Distance float64 `yaml:"distance"`
+	// Timeout is the maximum duration of the gesture, unlimited by default except for tap.
+	Timeout time.Duration `yaml:"timeout"`
+
+	CommandConfig CommandConfig `yaml:",inline"`
+}
+
+func (c *GestureConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain GestureConfig
+	c.CommandConfig.setNode(value)
+	return keepTypeError(value.Decode((*plain)(c)), &c.CommandConfig.errs)
+}
+
+// Name returns the gesture, the fingers and the direction of the trigger.
+func (c GestureConfig) Name() string {
+	s := fmt.Sprintf("%s %d", c.Gesture, c.FingersOrDefault())
+	if c.Direction != "" {
+		s += " " + string(c.Direction)
+	}
+	return s
+}
+
+func (c GestureConfig) FingersOrDefault() int {
+	if c.Fingers > 0 {
+		return c.Fingers
+	}
+	if c.Gesture == GesturePinch {
+		return 2
+	}
+	return 1
+}
+
+func (c GestureConfig) DistanceOrDefault() float64 {
+	if c.Distance > 0 {
+		return c.Distance
+	}
+	switch c.Gesture {
+	case GesturePinch:
+		return DefaultPinchDistance
+	case GestureTap:
+		return DefaultTapDistance
+	}
+	return DefaultSwipeDistance
+}
+
+// TimeoutOrDefault returns the timeout of the gesture, or 0 if it is unlimited.
+func (c GestureConfig) TimeoutOrDefault() time.Duration {
+	if c.Timeout == 0 && c.Gesture == GestureTap {
+		return DefaultTapTimeout
+	}
+	return c.Timeout
+}
+
+func (c GestureConfig) validate(global ProcessConfig) Errors {
+	name := fmt.Sprintf("gesture %s", c.Name())
+	errs := c.CommandConfig.validate(name, global, false)
+	switch c.Gesture {
+	case GestureSwipe:
+		switch c.Direction {
+		case GestureUp, GestureDown, GestureLeft, GestureRight:
+		default:
+			errs.add(c.CommandConfig.line, "%s: direction must be up, down, left or right", name)
+		}
+	case GesturePinch:
+		switch c.Direction {
+		case GestureIn, GestureOut:
+		default:
+			errs.add(c.CommandConfig.line, "%s: direction must be in or out", name)
+		}
+		if c.FingersOrDefault() < 2 {
+			errs.add(c.CommandConfig.line, "%s: pinch requires 2 fingers or more", name)
+		}
+	case GestureTap:
+		if c.Direction != "" {
+			errs.add(c.CommandConfig.line, "%s: tap has no direction", name)
+		}
+	default:
+		errs.add(c.CommandConfig.line, "gesture: unknown gesture %q", c.Gesture)
+	}
+	if c.Fingers < 0 {
+		errs.add(c.CommandConfig.line, "%s: fingers must not be negative", name)
+	}
+	if c.Distance < 0 {
+		errs.add(c.CommandConfig.line, "%s: distance must not be negative", name)
+	}
+	if c.Timeout < 0 {
+		errs.add(c.CommandConfig.line, "%s: timeout must not be negative", name)
+	}
+	return errs
+}
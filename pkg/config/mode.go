@@ -0,0 +1,171 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"gopkg.in/yaml.v3"
+)
+
+// ModeDefault is the mode without the triggers of Modes, which is active at first.
+const ModeDefault = "default"
+
+// ModeConfig is the triggers of a mode.
+type ModeConfig struct {
+	Bindings `yaml:",inline"`
+
+	line int
+}
+
+func (m *ModeConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain ModeConfig
+	m.line = value.Line
+	return value.Decode((*plain)(m))
+}
+
+// ModeAction is how a mode key switches the active mode.
+type ModeAction string
+
+const (
+	// ModeToggle switches to the mode by a press, and back to the default mode by the next press.
+	ModeToggle ModeAction = "toggle"
+	// ModeMomentary switches to the mode while the key is held.
+	ModeMomentary ModeAction = "momentary"
+	// ModeOneShot switches to the mode until the next key is released.
+	ModeOneShot ModeAction = "one_shot"
+)
+
+// KeyCode is an EV_KEY code.
+// In YAML, it is written as a number or a name such as KEY_F13.
+type KeyCode uint16
+
+func (c *KeyCode) UnmarshalYAML(value *yaml.Node) error {
+	code, err := ParseKeyCode(value.Value)
+	if err != nil {
+		return typeErrorf(value, "%s", err)
+	}
+	*c = KeyCode(code)
+	return nil
+}
+
+func (c KeyCode) String() string {
+	return evdev.CodeName(evdev.EV_KEY, uint16(c))
+}
+
+// ModeKeyConfig is a key which switches the active mode instead of firing triggers.
+type ModeKeyConfig struct {
+	Key KeyCode `yaml:"key"`
+	// Mode is a name of Modes, or ModeDefault.
+	Mode string `yaml:"mode"`
+	// Action defaults to toggle.
+	Action ModeAction `yaml:"action"`
+
+	line int
+	errs Errors
+}
+
+func (k *ModeKeyConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain ModeKeyConfig
+	k.line = value.Line
+	return keepTypeError(value.Decode((*plain)(k)), &k.errs)
+}
+
+func (k ModeKeyConfig) ActionOrDefault() ModeAction {
+	if k.Action == "" {
+		return ModeToggle
+	}
+	return k.Action
+}
+
+// HasMode reports whether mode is ModeDefault or a name of Modes.
+func (b Bindings) HasMode(mode string) bool {
+	_, ok := b.Modes[mode]
+	return ok || mode == ModeDefault
+}
+
+// ModeNames returns the names of Modes in order.
+func (b Bindings) ModeNames() []string {
+	names := make([]string, 0, len(b.Modes))
+	for name := range b.Modes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForMode returns the triggers active in the mode, which are the triggers of b and the mode.
+// The triggers and the mode keys of the mode take precedence over those of b for the same keys,
+// and the triggers of the mode return it by CommandConfig.Mode.
+func (b Bindings) ForMode(mode string) Bindings {
+	m := b.Modes[mode].Bindings.clone()
+	m.eachCommand(func(c *CommandConfig) {
+		c.mode = mode
+	})
+	overridden := make(map[uint16]bool, len(m.Triggers)+len(m.ModeKeys))
+	for code := range m.Triggers {
+		overridden[code] = true
+	}
+	for _, k := range m.ModeKeys {
+		overridden[uint16(k.Key)] = true
+	}
+
+	a := Bindings{
+		Triggers:  make(Triggers, len(b.Triggers)+len(m.Triggers)),
+		Chords:    append(append([]ChordConfig{}, b.Chords...), m.Chords...),
+		Sequences: append(append([]SequenceConfig{}, b.Sequences...), m.Sequences...),
+		Relative:  append(append([]RelativeConfig{}, b.Relative...), m.Relative...),
+		Absolute:  append(append([]AbsoluteConfig{}, b.Absolute...), m.Absolute...),
+		Switches:  append(append([]SwitchConfig{}, b.Switches...), m.Switches...),
+		Gestures:  append(append([]GestureConfig{}, b.Gestures...), m.Gestures...),
+	}
+	for code, ts := range b.Triggers {
+		if !overridden[code] {
+			a.Triggers[code] = ts
+		}
+	}
+	for code, ts := range m.Triggers {
+		a.Triggers[code] = ts
+	}
+	for _, k := range b.ModeKeys {
+		if !overridden[uint16(k.Key)] {
+			a.ModeKeys = append(a.ModeKeys, k)
+		}
+	}
+	a.ModeKeys = append(a.ModeKeys, m.ModeKeys...)
+	return a
+}
+
+// clone returns a copy of b whose triggers can be modified without b.
+func (b Bindings) clone() Bindings {
+	c := b
+	c.Triggers = make(Triggers, len(b.Triggers))
+	for code, ts := range b.Triggers {
+		c.Triggers[code] = append(KeyTriggers{}, ts...)
+	}
+	c.Chords = append([]ChordConfig{}, b.Chords...)
+	c.Sequences = append([]SequenceConfig{}, b.Sequences...)
+	c.Relative = append([]RelativeConfig{}, b.Relative...)
+	c.Absolute = append([]AbsoluteConfig{}, b.Absolute...)
+	c.Switches = append([]SwitchConfig{}, b.Switches...)
+	c.Gestures = append([]GestureConfig{}, b.Gestures...)
+	c.Modes = nil
+	return c
+}
+
+func (k ModeKeyConfig) validate(b Bindings, modes Bindings) Errors {
+	errs := append(Errors{}, k.errs...)
+	name := fmt.Sprintf("mode key %s", k.Key)
+	if !modes.HasMode(k.Mode) {
+		errs.add(k.line, "%s: unknown mode %q", name, k.Mode)
+	}
+	switch k.ActionOrDefault() {
+	case ModeToggle, ModeMomentary, ModeOneShot:
+	default:
+		errs.add(k.line, "%s: unknown action %q", name, k.Action)
+	}
+	if _, ok := b.Triggers[uint16(k.Key)]; ok {
+		errs.add(k.line, "%s: the key also has triggers", name)
+	}
+	return errs
+}
@@ -0,0 +1,210 @@
+package config
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ProcessConfig is the process attributes of commands.
+// It is set for each trigger and at the top level, where it is the default of all triggers.
+type ProcessConfig struct {
+	// Dir is the working directory of commands, the directory of evdev-trigger if empty.
+	Dir string    `yaml:"dir"`
+	Env EnvConfig `yaml:"env"`
+	// User runs commands as the user, the user of evdev-trigger if empty.
+	User string `yaml:"user"`
+	// Group runs commands with the group, the primary group of User if empty.
+	Group string `yaml:"group"`
+	// Session runs commands in the session of a logged in user, which decides the user.
+	Session Session `yaml:"session"`
+}
+
+// Session is the login session which commands run in.
+type Session string
+
+// SessionActiveUser runs commands as the user of the active session of the seat, with the environment of the session.
+const SessionActiveUser Session = "active-user"
+
+// EnvConfig is the environment variables of commands.
+type EnvConfig struct {
+	// Inherit passes the environment of evdev-trigger to commands, true if unset.
+	Inherit *bool `yaml:"inherit"`
+	// Set is the variables added to the environment.
+	Set map[string]string `yaml:"set"`
+	// Unset is the names of the inherited variables which are removed.
+	Unset []string `yaml:"unset"`
+}
+
+// InheritOrDefault returns Inherit, or true if it is not configured.
+func (e EnvConfig) InheritOrDefault() bool {
+	return e.Inherit == nil || *e.Inherit
+}
+
+// inherit fills the options of p which are not set by global, the options at the top level.
+// The variables of Env are merged, and the variables of p win.
+func (p *ProcessConfig) inherit(global ProcessConfig) {
+	if p.Dir == "" {
+		p.Dir = global.Dir
+	}
+	// the user and the session decide the user together, so a trigger with either of them inherits neither.
+	if p.User == "" && p.Session == "" {
+		p.User = global.User
+		p.Session = global.Session
+	}
+	if p.Group == "" {
+		p.Group = global.Group
+	}
+	if p.Env.Inherit == nil {
+		p.Env.Inherit = global.Env.Inherit
+	}
+	if len(global.Env.Set) > 0 {
+		set := make(map[string]string, len(global.Env.Set)+len(p.Env.Set))
+		for k, v := range global.Env.Set {
+			set[k] = v
+		}
+		for k, v := range p.Env.Set {
+			set[k] = v
+		}
+		p.Env.Set = set
+	}
+	p.Env.Unset = append(append([]string{}, global.Env.Unset...), p.Env.Unset...)
+}
+
+// validate checks p, and prefix is prepended to the messages.
+// line returns the line of the problem of an option by its key.
+func (p ProcessConfig) validate(line func(key string) int, prefix string) Errors {
+	var errs Errors
+	if p.Dir != "" {
+		if !filepath.IsAbs(p.Dir) {
+			errs.add(line("dir"), "%sdir %q must be an absolute path", prefix, p.Dir)
+		} else if fi, err := os.Stat(p.Dir); err != nil {
+			errs.add(line("dir"), "%sdir %q is not found: %s", prefix, p.Dir, err)
+		} else if !fi.IsDir() {
+			errs.add(line("dir"), "%sdir %q is not a directory", prefix, p.Dir)
+		}
+	}
+	for k := range p.Env.Set {
+		if !validEnvName(k) {
+			errs.add(line("env"), "%senv: invalid variable name %q", prefix, k)
+		}
+	}
+	for _, k := range p.Env.Unset {
+		if !validEnvName(k) {
+			errs.add(line("env"), "%senv: invalid variable name %q", prefix, k)
+		}
+	}
+	if p.User != "" {
+		if _, err := LookupUser(p.User); err != nil {
+			errs.add(line("user"), "%suser %q is not found: %s", prefix, p.User, err)
+		}
+	}
+	if p.Group != "" {
+		if _, err := LookupGroup(p.Group); err != nil {
+			errs.add(line("group"), "%sgroup %q is not found: %s", prefix, p.Group, err)
+		}
+	}
+	switch p.Session {
+	case "":
+	case SessionActiveUser:
+		if p.User != "" {
+			errs.add(line("session"), "%ssession %s cannot be used with user", prefix, p.Session)
+		}
+	default:
+		errs.add(line("session"), "%sunknown session %q", prefix, p.Session)
+	}
+	return errs
+}
+
+// LookPath finds the program of a command run with p, as the command would find it.
+// A relative path is resolved from Dir, and a name without slashes is searched in PATH of the environment of p.
+func LookPath(file string, p ProcessConfig) (string, error) {
+	if strings.Contains(file, "/") {
+		if !filepath.IsAbs(file) && p.Dir != "" {
+			file = filepath.Join(p.Dir, file)
+		}
+		if err := findExecutable(file); err != nil {
+			return "", &exec.Error{Name: file, Err: err}
+		}
+		return file, nil
+	}
+	for _, dir := range filepath.SplitList(p.envPath()) {
+		if dir == "" {
+			dir = "."
+		}
+		if !filepath.IsAbs(dir) && p.Dir != "" {
+			dir = filepath.Join(p.Dir, dir)
+		}
+		if path := filepath.Join(dir, file); findExecutable(path) == nil {
+			if !strings.Contains(path, "/") {
+				path = "." + string(filepath.Separator) + path
+			}
+			return path, nil
+		}
+	}
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+// envPath returns PATH of the environment of commands run with p.
+func (p ProcessConfig) envPath() string {
+	if path, ok := p.Env.Set["PATH"]; ok {
+		return path
+	}
+	if !p.Env.InheritOrDefault() {
+		return ""
+	}
+	for _, k := range p.Env.Unset {
+		if k == "PATH" {
+			return ""
+		}
+	}
+	return os.Getenv("PATH")
+}
+
+func findExecutable(file string) error {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() || fi.Mode()&0o111 == 0 {
+		return os.ErrPermission
+	}
+	return nil
+}
+
+func validEnvName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, "=\x00")
+}
+
+// LookupUser looks up the user by the name or the numeric id.
+func LookupUser(name string) (*user.User, error) {
+	u, err := user.Lookup(name)
+	if err == nil {
+		return u, nil
+	}
+	if _, perr := strconv.ParseUint(name, 10, 32); perr != nil {
+		return nil, err
+	}
+	if u, err := user.LookupId(name); err == nil {
+		return u, nil
+	}
+	return nil, err
+}
+
+// LookupGroup looks up the group by the name or the numeric id.
+func LookupGroup(name string) (*user.Group, error) {
+	g, err := user.LookupGroup(name)
+	if err == nil {
+		return g, nil
+	}
+	if _, perr := strconv.ParseUint(name, 10, 32); perr != nil {
+		return nil, err
+	}
+	if g, err := user.LookupGroupId(name); err == nil {
+		return g, nil
+	}
+	return nil, err
+}
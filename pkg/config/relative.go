@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"gopkg.in/yaml.v3"
+)
+
+// RelCode is an EV_REL code.
+// In YAML, it is written as a number or a name such as REL_WHEEL.
+type RelCode uint16
+
+func (c *RelCode) UnmarshalYAML(value *yaml.Node) error {
+	code, err := parseCode(value.Value, evdev.EV_REL)
+	if err != nil {
+		return typeErrorf(value, "%s", err)
+	}
+	*c = RelCode(code)
+	return nil
+}
+
+func (c RelCode) String() string {
+	return evdev.CodeName(evdev.EV_REL, uint16(c))
+}
+
+// Direction is the direction of movements on a relative axis.
+type Direction string
+
+const (
+	// DirectionPositive is movements with positive values, such as scrolling up a wheel.
+	DirectionPositive Direction = "positive"
+	// DirectionNegative is movements with negative values, such as scrolling down a wheel.
+	DirectionNegative Direction = "negative"
+)
+
+// RelativeConfig is a trigger fired by movements on a relative axis, such as a wheel or a rotary encoder.
+// The values of events are accumulated, and the trigger fires every time the accumulated delta reaches the threshold.
+// The command gets the delta consumed by the run in EVDEV_DELTA.
+type RelativeConfig struct {
+	Axis RelCode `yaml:"axis"`
+	// Direction limits the trigger to movements in the direction, both directions if empty.
+	// The accumulated delta is reset when the direction changes.
+	Direction Direction `yaml:"direction"`
+	// Threshold is the accumulated delta to fire the trigger, 1 if zero.
+	Threshold int32 `yaml:"threshold"`
+
+	CommandConfig CommandConfig `yaml:",inline"`
+}
+
+func (c *RelativeConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain RelativeConfig
+	c.CommandConfig.setNode(value)
+	return keepTypeError(value.Decode((*plain)(c)), &c.CommandConfig.errs)
+}
+
+// Name returns the axis and the direction of the trigger.
+func (c RelativeConfig) Name() string {
+	if c.Direction == "" {
+		return c.Axis.String()
+	}
+	return fmt.Sprintf("%s %s", c.Axis, c.Direction)
+}
+
+// ThresholdOrDefault returns Threshold, or 1 if it is not configured.
+func (c RelativeConfig) ThresholdOrDefault() int32 {
+	if c.Threshold > 0 {
+		return c.Threshold
+	}
+	return 1
+}
+
+func (c RelativeConfig) validate(global ProcessConfig) Errors {
+	name := fmt.Sprintf("relative %s", c.Name())
+	errs := c.CommandConfig.validate(name, global, false)
+	switch c.Direction {
+	case "", DirectionPositive, DirectionNegative:
+	default:
+		errs.add(c.CommandConfig.line, "relative %s: unknown direction %q", c.Axis, c.Direction)
+	}
+	if c.Threshold < 0 {
+		errs.add(c.CommandConfig.line, "%s: threshold must not be negative", name)
+	}
+	return errs
+}
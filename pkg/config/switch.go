@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"gopkg.in/yaml.v3"
+)
+
+// SwCode is an EV_SW code.
+// In YAML, it is written as a number or a name such as SW_LID.
+type SwCode uint16
+
+func (c *SwCode) UnmarshalYAML(value *yaml.Node) error {
+	code, err := parseCode(value.Value, evdev.EV_SW)
+	if err != nil {
+		return typeErrorf(value, "%s", err)
+	}
+	*c = SwCode(code)
+	return nil
+}
+
+func (c SwCode) String() string {
+	return evdev.CodeName(evdev.EV_SW, uint16(c))
+}
+
+// SwitchState is the state of a switch.
+type SwitchState string
+
+const (
+	SwitchOn  SwitchState = "on"
+	SwitchOff SwitchState = "off"
+)
+
+// SwitchConfig is a trigger fired when a switch, such as a lid or a headphone jack, turns into the state.
+// When the device is reconnected, the trigger also fires if the switch turned into the state while disconnected.
+type SwitchConfig struct {
+	Switch SwCode      `yaml:"switch"`
+	State  SwitchState `yaml:"state"`
+	// Initial fires the trigger at the first connection of the device if the switch is in the state.
+	Initial bool `yaml:"initial"`
+
+	CommandConfig CommandConfig `yaml:",inline"`
+}
+
+func (c *SwitchConfig) UnmarshalYAML(value *yaml.Node) error {
+	type plain SwitchConfig
+	c.CommandConfig.setNode(value)
+	return keepTypeError(value.Decode((*plain)(c)), &c.CommandConfig.errs)
+}
+
+// Name returns the switch and the state of the trigger.
+func (c SwitchConfig) Name() string {
+	return fmt.Sprintf("%s %s", c.Switch, c.State)
+}
+
+// Fires reports whether the switch being on fires the trigger.
+func (c SwitchConfig) Fires(on bool) bool {
+	return on == (c.State == SwitchOn)
+}
+
+func (c SwitchConfig) validate(global ProcessConfig) Errors {
+	name := fmt.Sprintf("switch %s", c.Name())
+	errs := c.CommandConfig.validate(name, global, false)
+	switch c.State {
+	case SwitchOn, SwitchOff:
+	default:
+		errs.add(c.CommandConfig.line, "switch %s: unknown state %q", c.Switch, c.State)
+	}
+	return errs
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// CommandData is the data of the placeholders in the arguments of commands, such as {{.CodeName}}.
+type CommandData struct {
+	// Trigger is the name of the trigger.
+	Trigger string
+	// Type is the name of the event type such as EV_KEY.
+	Type string
+	Code uint16
+	// CodeName is the name of the event code such as KEY_VOLUMEUP.
+	CodeName string
+	Value    int32
+	Time     time.Time
+	// Delta is the delta consumed by a relative trigger, zero for the other triggers.
+	Delta      int32
+	DeviceName string
+	DevicePhys string
+	DevicePath string
+}
+
+// Render returns the command with the placeholders in the arguments replaced by d.
+// The program, the first argument, is used as it is.
+func (c Command) Render(d CommandData) (Command, error) {
+	rendered := make(Command, len(c))
+	for i, arg := range c {
+		if i == 0 || !strings.Contains(arg, "{{") {
+			rendered[i] = arg
+			continue
+		}
+		tmpl, err := template.New("").Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, err
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, d); err != nil {
+			return nil, err
+		}
+		rendered[i] = b.String()
+	}
+	return rendered, nil
+}
+
+// hasPlaceholders reports whether s has placeholders.
+// s which is not a valid template, such as an awk script, has none.
+func hasPlaceholders(s string) bool {
+	tmpl, err := template.New("").Parse(s)
+	if err != nil || tmpl.Tree == nil {
+		return false
+	}
+	for _, n := range tmpl.Tree.Root.Nodes {
+		if _, ok := n.(*parse.TextNode); !ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,374 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"gopkg.in/yaml.v3"
+)
+
+// Error is a problem found in a configuration file.
+type Error struct {
+	// Line is the line number of the problem, or 0 if it is unknown.
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+// Errors is all problems found in a configuration file.
+type Errors []*Error
+
+func (e Errors) Error() string {
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+func (e *Errors) add(line int, format string, args ...interface{}) {
+	*e = append(*e, &Error{
+		Line: line,
+		Msg:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (e Errors) sort() {
+	sort.SliceStable(e, func(i, j int) bool {
+		return e[i].Line < e[j].Line
+	})
+}
+
+// keepTypeError moves the problems of a yaml.TypeError into errs,
+// so that a partially decoded value is kept and validated further.
+// Read reports the kept problems.
+func keepTypeError(err error, errs *Errors) error {
+	var te *yaml.TypeError
+	if errors.As(err, &te) {
+		*errs = append(*errs, parseTypeError(te)...)
+		return nil
+	}
+	return err
+}
+
+func typeErrorf(n *yaml.Node, format string, args ...interface{}) error {
+	return &yaml.TypeError{Errors: []string{lineError(n, fmt.Sprintf(format, args...))}}
+}
+
+// lineError formats msg like errors in yaml.TypeError.
+// keyLines returns the lines of the keys of the mapping n.
+func keyLines(n *yaml.Node) map[string]int {
+	lines := make(map[string]int, len(n.Content)/2)
+	if n.Kind != yaml.MappingNode {
+		return lines
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		lines[n.Content[i].Value] = n.Content[i].Line
+	}
+	return lines
+}
+
+func lineError(n *yaml.Node, msg string) string {
+	return fmt.Sprintf("line %d: %s", n.Line, msg)
+}
+
+var lineErrorRe = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+func parseTypeError(te *yaml.TypeError) Errors {
+	errs := make(Errors, 0, len(te.Errors))
+	for _, s := range te.Errors {
+		m := lineErrorRe.FindStringSubmatch(s)
+		if m == nil {
+			errs.add(0, "%s", s)
+			continue
+		}
+		line, _ := strconv.Atoi(m[1])
+		errs.add(line, "%s", m[2])
+	}
+	return errs
+}
+
+// unknownFields reports mapping keys in n which are not fields of t.
+// yaml.v3 cannot check unknown fields through custom unmarshalers,
+// so n is walked together with t instead.
+func unknownFields(n *yaml.Node, t reflect.Type) Errors {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var errs Errors
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			errs = append(errs, unknownFields(c, t)...)
+		}
+	case yaml.SequenceNode:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for _, c := range n.Content {
+				errs = append(errs, unknownFields(c, t.Elem())...)
+			}
+		}
+	case yaml.MappingNode:
+		switch t.Kind() {
+		case reflect.Map:
+			for i := 1; i < len(n.Content); i += 2 {
+				errs = append(errs, unknownFields(n.Content[i], t.Elem())...)
+			}
+		case reflect.Slice:
+			// a mapping is the single element form of a sequence, such as KeyTriggers.
+			errs = append(errs, unknownFields(n, t.Elem())...)
+		case reflect.Struct:
+			fields := yamlFields(t)
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k, v := n.Content[i], n.Content[i+1]
+				ft, ok := fields[k.Value]
+				if !ok {
+					errs.add(k.Line, "field %s not found in type %s", k.Value, t)
+					continue
+				}
+				errs = append(errs, unknownFields(v, ft)...)
+			}
+		}
+	}
+	return errs
+}
+
+// yamlFields returns the types of fields of struct t by their YAML keys.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("yaml"), ",")
+		switch {
+		case tag[0] == "-":
+			continue
+		case len(tag) > 1 && tag[1] == "inline":
+			for k, v := range yamlFields(f.Type) {
+				fields[k] = v
+			}
+		case tag[0] != "":
+			fields[tag[0]] = f.Type
+		default:
+			fields[strings.ToLower(f.Name)] = f.Type
+		}
+	}
+	return fields
+}
+
+func (c *Config) validate() Errors {
+	var errs Errors
+	if c.Concurrency < 0 {
+		errs.add(c.keyLine("concurrency"), "concurrency must not be negative")
+	}
+	if c.GracePeriod < 0 {
+		errs.add(c.keyLine("grace_period"), "grace_period must not be negative")
+	}
+	errs = append(errs, c.ProcessConfig.validate(c.keyLine, "")...)
+	labels := make(map[string]bool, len(c.Devices))
+	for _, d := range c.Devices {
+		errs = append(errs, d.errs...)
+		if err := d.Match.Validate(); err != nil {
+			errs.add(d.line, "%s", err)
+		}
+		// labels identify devices on reloading.
+		if labels[d.Label()] {
+			errs.add(d.line, "duplicated device %q", d.Label())
+		}
+		labels[d.Label()] = true
+
+		errs = append(errs, d.Bindings.validate(c.ProcessConfig)...)
+	}
+	return errs
+}
+
+// validate validates b, and global is the process attributes at the top level.
+func (b Bindings) validate(global ProcessConfig) Errors {
+	errs := b.validateTriggers(b, global)
+	for _, name := range b.ModeNames() {
+		m := b.Modes[name]
+		if name == ModeDefault {
+			errs.add(m.line, "mode %s: the name is reserved", name)
+		}
+		if len(m.Modes) > 0 {
+			errs.add(m.line, "mode %s: modes cannot be nested", name)
+		}
+		errs = append(errs, m.validateTriggers(b, global)...)
+	}
+	return errs
+}
+
+// validateTriggers validates the triggers of b, whose mode keys switch to the modes of modes.
+func (b Bindings) validateTriggers(modes Bindings, global ProcessConfig) Errors {
+	var errs Errors
+	for code, ts := range b.Triggers {
+		for _, t := range ts {
+			errs = append(errs, t.validate(code, global)...)
+		}
+	}
+	for _, c := range b.Chords {
+		errs = append(errs, c.validate(global)...)
+	}
+	for _, c := range b.Sequences {
+		errs = append(errs, c.validate(global)...)
+	}
+	for _, c := range b.Relative {
+		errs = append(errs, c.validate(global)...)
+	}
+	for _, c := range b.Absolute {
+		errs = append(errs, c.validate(global)...)
+	}
+	for _, c := range b.Switches {
+		errs = append(errs, c.validate(global)...)
+	}
+	for _, c := range b.Gestures {
+		errs = append(errs, c.validate(global)...)
+	}
+	for _, k := range b.ModeKeys {
+		errs = append(errs, k.validate(b, modes)...)
+	}
+	return errs
+}
+
+func (t KeyTrigger) validate(code uint16, global ProcessConfig) Errors {
+	name := t.Name(code)
+	errs := t.CommandConfig.validate(name, global, true)
+	on := t.OnOrDefault()
+	seen := make(map[Edge]bool, len(on))
+	for _, e := range on {
+		if seen[e] {
+			errs.add(t.CommandConfig.line, "%s: on %s is duplicated", name, e)
+			continue
+		}
+		seen[e] = true
+		switch e {
+		case EdgePress, EdgeRelease, EdgeRepeat:
+		case EdgeShortPress, EdgeLongPress:
+			if len(on) > 1 {
+				errs.add(t.CommandConfig.line, "%s: on %s cannot be combined with other edges", name, e)
+			}
+		default:
+			errs.add(t.CommandConfig.line, "%s: unknown on %q", evdev.CodeName(evdev.EV_KEY, code), e)
+		}
+	}
+	if t.Threshold < 0 {
+		errs.add(t.CommandConfig.line, "%s: threshold must not be negative", name)
+	}
+	if t.WhileHeld && !on.Has(EdgeLongPress) {
+		errs.add(t.CommandConfig.line, "%s: while_held requires on %s", name, EdgeLongPress)
+	}
+	if t.CommandConfig.Repeat != nil && !on.Has(EdgePress) && !(on.Has(EdgeLongPress) && t.WhileHeld) {
+		errs.add(t.CommandConfig.line, "%s: repeat requires on %s, or %s with while_held", name, EdgePress, EdgeLongPress)
+	}
+	if t.Taps < 0 {
+		errs.add(t.CommandConfig.line, "%s: taps must not be negative", name)
+	}
+	if t.Taps > 1 && !on.Has(EdgeRelease) && !on.Has(EdgeShortPress) {
+		errs.add(t.CommandConfig.line, "%s: taps requires on %s or %s", name, EdgeRelease, EdgeShortPress)
+	}
+	if t.TapWindow < 0 {
+		errs.add(t.CommandConfig.line, "%s: tap_window must not be negative", name)
+	}
+	return errs
+}
+
+func (c ChordConfig) validate(global ProcessConfig) Errors {
+	name := fmt.Sprintf("chord %s", c.Name())
+	errs := c.CommandConfig.validate(name, global, true)
+	if len(c.Keys) == 0 {
+		errs.add(c.CommandConfig.line, "%s: keys are empty", name)
+	}
+	switch c.Strictness {
+	case "", StrictnessExact, StrictnessSuperset:
+	default:
+		errs.add(c.CommandConfig.line, "%s: unknown strictness %q", name, c.Strictness)
+	}
+	return errs
+}
+
+// validate validates c, and global is the process attributes at the top level, which c inherits.
+// repeatable is true for the triggers fired by holding keys, which can use repeat.
+func (c CommandConfig) validate(name string, global ProcessConfig, repeatable bool) Errors {
+	errs := append(Errors{}, c.errs...)
+	p := c.ProcessConfig
+	p.inherit(global)
+	if len(c.Command) == 0 {
+		errs.add(c.line, "%s: command is empty", name)
+	} else if _, err := LookPath(c.Command[0], p); err != nil {
+		errs.add(c.line, "%s: command %q is not found: %s", name, c.Command[0], err)
+	}
+	if c.script {
+		// the values of devices, such as their names, could inject commands into the shell.
+		if len(c.Command) > 0 && hasPlaceholders(c.Command[len(c.Command)-1]) {
+			errs.add(c.line, "%s: placeholders cannot be used in command written as a string, use the environment variables instead", name)
+		}
+	} else if _, err := c.Command.Render(CommandData{}); err != nil {
+		// the placeholders are checked with the zero data, so that a typo fails on loading.
+		errs.add(c.line, "%s: command has an invalid placeholder: %s", name, err)
+	}
+	if c.Shell != "" && !c.script {
+		errs.add(c.line, "%s: shell requires command written as a string", name)
+	}
+	if c.Interval < 0 {
+		errs.add(c.line, "%s: interval must not be negative", name)
+	}
+	if c.Concurrency < 0 {
+		errs.add(c.line, "%s: concurrency must not be negative", name)
+	}
+	if c.Timeout < 0 {
+		errs.add(c.line, "%s: command_timeout must not be negative", name)
+	}
+	switch c.Overlap {
+	case "", OverlapQueue, OverlapDrop, OverlapRestart:
+	case OverlapParallel:
+		if c.Concurrency > 0 {
+			errs.add(c.line, "%s: overlap %s cannot be used with concurrency", name, c.Overlap)
+		}
+	default:
+		errs.add(c.line, "%s: unknown overlap %q", name, c.Overlap)
+	}
+	switch c.Stdin {
+	case "", StdinEventJSON:
+	default:
+		errs.add(c.line, "%s: unknown stdin %q", name, c.Stdin)
+	}
+	errs = append(errs, c.ProcessConfig.validate(func(string) int { return c.line }, name+": ")...)
+	if c.Repeat != nil && !repeatable {
+		errs.add(c.line, "%s: repeat can be used only with keys, chords and sequences", name)
+	}
+	if r := c.Repeat; r != nil {
+		if r.Period <= 0 {
+			errs.add(c.line, "%s: repeat period must be positive", name)
+		}
+		if r.Delay < 0 || r.Max < 0 {
+			errs.add(c.line, "%s: repeat delay and max must not be negative", name)
+		}
+	}
+	return errs
+}
+
+func (c SequenceConfig) validate(global ProcessConfig) Errors {
+	name := fmt.Sprintf("sequence %s", c.Name())
+	errs := c.CommandConfig.validate(name, global, true)
+	if len(c.Keys) == 0 {
+		errs.add(c.CommandConfig.line, "%s: keys are empty", name)
+	}
+	if c.Timeout < 0 {
+		errs.add(c.CommandConfig.line, "%s: timeout must not be negative", name)
+	}
+	return errs
+}
@@ -0,0 +1,37 @@
+package evdev
+
+import "fmt"
+
+// Code identifies an event code together with its event type.
+type Code struct {
+	Type uint16
+	Code uint16
+}
+
+func (c Code) String() string {
+	return CodeName(c.Type, c.Code)
+}
+
+// TypeName returns the name of the event type such as EV_KEY.
+// It returns the number if the type has no name.
+func TypeName(typ uint16) string {
+	if n, ok := typeNames[typ]; ok {
+		return n
+	}
+	return fmt.Sprintf("EV_%d", typ)
+}
+
+// CodeName returns the name of the event code such as KEY_VOLUMEUP.
+// It returns the number if the code has no name.
+func CodeName(typ, code uint16) string {
+	if n, ok := codeNames[typ][code]; ok {
+		return n
+	}
+	return fmt.Sprintf("%d", code)
+}
+
+// LookupCode returns the event code named name such as KEY_VOLUMEUP or BTN_LEFT.
+func LookupCode(name string) (Code, bool) {
+	c, ok := namedCodes[name]
+	return c, ok
+}
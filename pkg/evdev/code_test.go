@@ -0,0 +1,50 @@
+package evdev_test
+
+import (
+	"testing"
+
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupCode(t *testing.T) {
+	tests := []struct {
+		name string
+		want evdev.Code
+	}{
+		{"KEY_VOLUMEUP", evdev.Code{Type: evdev.EV_KEY, Code: 115}},
+		{"BTN_LEFT", evdev.Code{Type: evdev.EV_KEY, Code: 0x110}},
+		{"BTN_MOUSE", evdev.Code{Type: evdev.EV_KEY, Code: 0x110}},
+		{"BTN_A", evdev.Code{Type: evdev.EV_KEY, Code: 0x130}},
+		{"REL_WHEEL", evdev.Code{Type: evdev.EV_REL, Code: 0x08}},
+		{"ABS_MT_SLOT", evdev.Code{Type: evdev.EV_ABS, Code: 0x2f}},
+		{"SW_LID", evdev.Code{Type: evdev.EV_SW, Code: 0x00}},
+		{"FF_RUMBLE", evdev.Code{Type: evdev.EV_FF, Code: 0x50}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := evdev.LookupCode(tt.name)
+			require.True(t, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	_, ok := evdev.LookupCode("KEY_MAX")
+	require.False(t, ok)
+}
+
+func TestCodeName(t *testing.T) {
+	// the constants agree with the generated tables.
+	require.Equal(t, "SYN_REPORT", evdev.CodeName(evdev.EV_SYN, evdev.SYN_REPORT))
+	require.Equal(t, "ABS_MT_SLOT", evdev.CodeName(evdev.EV_ABS, evdev.ABS_MT_SLOT))
+	require.Equal(t, "ABS_MT_POSITION_X", evdev.CodeName(evdev.EV_ABS, evdev.ABS_MT_POSITION_X))
+	require.Equal(t, "ABS_MT_POSITION_Y", evdev.CodeName(evdev.EV_ABS, evdev.ABS_MT_POSITION_Y))
+	require.Equal(t, "ABS_MT_TRACKING_ID", evdev.CodeName(evdev.EV_ABS, evdev.ABS_MT_TRACKING_ID))
+	require.Equal(t, "KEY_VOLUMEUP", evdev.CodeName(evdev.EV_KEY, 115))
+	require.Equal(t, "BTN_LEFT", evdev.CodeName(evdev.EV_KEY, 0x110))
+	require.Equal(t, "BTN_SOUTH", evdev.CodeName(evdev.EV_KEY, 0x130))
+	require.Equal(t, "REL_WHEEL", evdev.CodeName(evdev.EV_REL, 0x08))
+	require.Equal(t, "SYN_REPORT", evdev.CodeName(evdev.EV_SYN, 0))
+	require.Equal(t, "1023", evdev.CodeName(evdev.EV_KEY, 1023))
+	require.Equal(t, "EV_KEY", evdev.TypeName(evdev.EV_KEY))
+}
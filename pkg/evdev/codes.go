@@ -0,0 +1,1517 @@
+// Code generated by gen_codes.go; DO NOT EDIT.
+
+package evdev
+
+var typeNames = map[uint16]string{
+	0x00: "EV_SYN",
+	0x01: "EV_KEY",
+	0x02: "EV_REL",
+	0x03: "EV_ABS",
+	0x04: "EV_MSC",
+	0x05: "EV_SW",
+	0x11: "EV_LED",
+	0x12: "EV_SND",
+	0x14: "EV_REP",
+	0x15: "EV_FF",
+	0x16: "EV_PWR",
+	0x17: "EV_FF_STATUS",
+}
+
+var codeNames = map[uint16]map[uint16]string{
+	EV_SYN: {
+		0x000: "SYN_REPORT",
+		0x001: "SYN_CONFIG",
+		0x002: "SYN_MT_REPORT",
+		0x003: "SYN_DROPPED",
+	},
+	EV_KEY: {
+		0x000: "KEY_RESERVED",
+		0x001: "KEY_ESC",
+		0x002: "KEY_1",
+		0x003: "KEY_2",
+		0x004: "KEY_3",
+		0x005: "KEY_4",
+		0x006: "KEY_5",
+		0x007: "KEY_6",
+		0x008: "KEY_7",
+		0x009: "KEY_8",
+		0x00a: "KEY_9",
+		0x00b: "KEY_0",
+		0x00c: "KEY_MINUS",
+		0x00d: "KEY_EQUAL",
+		0x00e: "KEY_BACKSPACE",
+		0x00f: "KEY_TAB",
+		0x010: "KEY_Q",
+		0x011: "KEY_W",
+		0x012: "KEY_E",
+		0x013: "KEY_R",
+		0x014: "KEY_T",
+		0x015: "KEY_Y",
+		0x016: "KEY_U",
+		0x017: "KEY_I",
+		0x018: "KEY_O",
+		0x019: "KEY_P",
+		0x01a: "KEY_LEFTBRACE",
+		0x01b: "KEY_RIGHTBRACE",
+		0x01c: "KEY_ENTER",
+		0x01d: "KEY_LEFTCTRL",
+		0x01e: "KEY_A",
+		0x01f: "KEY_S",
+		0x020: "KEY_D",
+		0x021: "KEY_F",
+		0x022: "KEY_G",
+		0x023: "KEY_H",
+		0x024: "KEY_J",
+		0x025: "KEY_K",
+		0x026: "KEY_L",
+		0x027: "KEY_SEMICOLON",
+		0x028: "KEY_APOSTROPHE",
+		0x029: "KEY_GRAVE",
+		0x02a: "KEY_LEFTSHIFT",
+		0x02b: "KEY_BACKSLASH",
+		0x02c: "KEY_Z",
+		0x02d: "KEY_X",
+		0x02e: "KEY_C",
+		0x02f: "KEY_V",
+		0x030: "KEY_B",
+		0x031: "KEY_N",
+		0x032: "KEY_M",
+		0x033: "KEY_COMMA",
+		0x034: "KEY_DOT",
+		0x035: "KEY_SLASH",
+		0x036: "KEY_RIGHTSHIFT",
+		0x037: "KEY_KPASTERISK",
+		0x038: "KEY_LEFTALT",
+		0x039: "KEY_SPACE",
+		0x03a: "KEY_CAPSLOCK",
+		0x03b: "KEY_F1",
+		0x03c: "KEY_F2",
+		0x03d: "KEY_F3",
+		0x03e: "KEY_F4",
+		0x03f: "KEY_F5",
+		0x040: "KEY_F6",
+		0x041: "KEY_F7",
+		0x042: "KEY_F8",
+		0x043: "KEY_F9",
+		0x044: "KEY_F10",
+		0x045: "KEY_NUMLOCK",
+		0x046: "KEY_SCROLLLOCK",
+		0x047: "KEY_KP7",
+		0x048: "KEY_KP8",
+		0x049: "KEY_KP9",
+		0x04a: "KEY_KPMINUS",
+		0x04b: "KEY_KP4",
+		0x04c: "KEY_KP5",
+		0x04d: "KEY_KP6",
+		0x04e: "KEY_KPPLUS",
+		0x04f: "KEY_KP1",
+		0x050: "KEY_KP2",
+		0x051: "KEY_KP3",
+		0x052: "KEY_KP0",
+		0x053: "KEY_KPDOT",
+		0x055: "KEY_ZENKAKUHANKAKU",
+		0x056: "KEY_102ND",
+		0x057: "KEY_F11",
+		0x058: "KEY_F12",
+		0x059: "KEY_RO",
+		0x05a: "KEY_KATAKANA",
+		0x05b: "KEY_HIRAGANA",
+		0x05c: "KEY_HENKAN",
+		0x05d: "KEY_KATAKANAHIRAGANA",
+		0x05e: "KEY_MUHENKAN",
+		0x05f: "KEY_KPJPCOMMA",
+		0x060: "KEY_KPENTER",
+		0x061: "KEY_RIGHTCTRL",
+		0x062: "KEY_KPSLASH",
+		0x063: "KEY_SYSRQ",
+		0x064: "KEY_RIGHTALT",
+		0x065: "KEY_LINEFEED",
+		0x066: "KEY_HOME",
+		0x067: "KEY_UP",
+		0x068: "KEY_PAGEUP",
+		0x069: "KEY_LEFT",
+		0x06a: "KEY_RIGHT",
+		0x06b: "KEY_END",
+		0x06c: "KEY_DOWN",
+		0x06d: "KEY_PAGEDOWN",
+		0x06e: "KEY_INSERT",
+		0x06f: "KEY_DELETE",
+		0x070: "KEY_MACRO",
+		0x071: "KEY_MUTE",
+		0x072: "KEY_VOLUMEDOWN",
+		0x073: "KEY_VOLUMEUP",
+		0x074: "KEY_POWER",
+		0x075: "KEY_KPEQUAL",
+		0x076: "KEY_KPPLUSMINUS",
+		0x077: "KEY_PAUSE",
+		0x078: "KEY_SCALE",
+		0x079: "KEY_KPCOMMA",
+		0x07a: "KEY_HANGEUL",
+		0x07b: "KEY_HANJA",
+		0x07c: "KEY_YEN",
+		0x07d: "KEY_LEFTMETA",
+		0x07e: "KEY_RIGHTMETA",
+		0x07f: "KEY_COMPOSE",
+		0x080: "KEY_STOP",
+		0x081: "KEY_AGAIN",
+		0x082: "KEY_PROPS",
+		0x083: "KEY_UNDO",
+		0x084: "KEY_FRONT",
+		0x085: "KEY_COPY",
+		0x086: "KEY_OPEN",
+		0x087: "KEY_PASTE",
+		0x088: "KEY_FIND",
+		0x089: "KEY_CUT",
+		0x08a: "KEY_HELP",
+		0x08b: "KEY_MENU",
+		0x08c: "KEY_CALC",
+		0x08d: "KEY_SETUP",
+		0x08e: "KEY_SLEEP",
+		0x08f: "KEY_WAKEUP",
+		0x090: "KEY_FILE",
+		0x091: "KEY_SENDFILE",
+		0x092: "KEY_DELETEFILE",
+		0x093: "KEY_XFER",
+		0x094: "KEY_PROG1",
+		0x095: "KEY_PROG2",
+		0x096: "KEY_WWW",
+		0x097: "KEY_MSDOS",
+		0x098: "KEY_COFFEE",
+		0x099: "KEY_ROTATE_DISPLAY",
+		0x09a: "KEY_CYCLEWINDOWS",
+		0x09b: "KEY_MAIL",
+		0x09c: "KEY_BOOKMARKS",
+		0x09d: "KEY_COMPUTER",
+		0x09e: "KEY_BACK",
+		0x09f: "KEY_FORWARD",
+		0x0a0: "KEY_CLOSECD",
+		0x0a1: "KEY_EJECTCD",
+		0x0a2: "KEY_EJECTCLOSECD",
+		0x0a3: "KEY_NEXTSONG",
+		0x0a4: "KEY_PLAYPAUSE",
+		0x0a5: "KEY_PREVIOUSSONG",
+		0x0a6: "KEY_STOPCD",
+		0x0a7: "KEY_RECORD",
+		0x0a8: "KEY_REWIND",
+		0x0a9: "KEY_PHONE",
+		0x0aa: "KEY_ISO",
+		0x0ab: "KEY_CONFIG",
+		0x0ac: "KEY_HOMEPAGE",
+		0x0ad: "KEY_REFRESH",
+		0x0ae: "KEY_EXIT",
+		0x0af: "KEY_MOVE",
+		0x0b0: "KEY_EDIT",
+		0x0b1: "KEY_SCROLLUP",
+		0x0b2: "KEY_SCROLLDOWN",
+		0x0b3: "KEY_KPLEFTPAREN",
+		0x0b4: "KEY_KPRIGHTPAREN",
+		0x0b5: "KEY_NEW",
+		0x0b6: "KEY_REDO",
+		0x0b7: "KEY_F13",
+		0x0b8: "KEY_F14",
+		0x0b9: "KEY_F15",
+		0x0ba: "KEY_F16",
+		0x0bb: "KEY_F17",
+		0x0bc: "KEY_F18",
+		0x0bd: "KEY_F19",
+		0x0be: "KEY_F20",
+		0x0bf: "KEY_F21",
+		0x0c0: "KEY_F22",
+		0x0c1: "KEY_F23",
+		0x0c2: "KEY_F24",
+		0x0c8: "KEY_PLAYCD",
+		0x0c9: "KEY_PAUSECD",
+		0x0ca: "KEY_PROG3",
+		0x0cb: "KEY_PROG4",
+		0x0cc: "KEY_ALL_APPLICATIONS",
+		0x0cd: "KEY_SUSPEND",
+		0x0ce: "KEY_CLOSE",
+		0x0cf: "KEY_PLAY",
+		0x0d0: "KEY_FASTFORWARD",
+		0x0d1: "KEY_BASSBOOST",
+		0x0d2: "KEY_PRINT",
+		0x0d3: "KEY_HP",
+		0x0d4: "KEY_CAMERA",
+		0x0d5: "KEY_SOUND",
+		0x0d6: "KEY_QUESTION",
+		0x0d7: "KEY_EMAIL",
+		0x0d8: "KEY_CHAT",
+		0x0d9: "KEY_SEARCH",
+		0x0da: "KEY_CONNECT",
+		0x0db: "KEY_FINANCE",
+		0x0dc: "KEY_SPORT",
+		0x0dd: "KEY_SHOP",
+		0x0de: "KEY_ALTERASE",
+		0x0df: "KEY_CANCEL",
+		0x0e0: "KEY_BRIGHTNESSDOWN",
+		0x0e1: "KEY_BRIGHTNESSUP",
+		0x0e2: "KEY_MEDIA",
+		0x0e3: "KEY_SWITCHVIDEOMODE",
+		0x0e4: "KEY_KBDILLUMTOGGLE",
+		0x0e5: "KEY_KBDILLUMDOWN",
+		0x0e6: "KEY_KBDILLUMUP",
+		0x0e7: "KEY_SEND",
+		0x0e8: "KEY_REPLY",
+		0x0e9: "KEY_FORWARDMAIL",
+		0x0ea: "KEY_SAVE",
+		0x0eb: "KEY_DOCUMENTS",
+		0x0ec: "KEY_BATTERY",
+		0x0ed: "KEY_BLUETOOTH",
+		0x0ee: "KEY_WLAN",
+		0x0ef: "KEY_UWB",
+		0x0f0: "KEY_UNKNOWN",
+		0x0f1: "KEY_VIDEO_NEXT",
+		0x0f2: "KEY_VIDEO_PREV",
+		0x0f3: "KEY_BRIGHTNESS_CYCLE",
+		0x0f4: "KEY_BRIGHTNESS_AUTO",
+		0x0f5: "KEY_DISPLAY_OFF",
+		0x0f6: "KEY_WWAN",
+		0x0f7: "KEY_RFKILL",
+		0x0f8: "KEY_MICMUTE",
+		0x100: "BTN_0",
+		0x101: "BTN_1",
+		0x102: "BTN_2",
+		0x103: "BTN_3",
+		0x104: "BTN_4",
+		0x105: "BTN_5",
+		0x106: "BTN_6",
+		0x107: "BTN_7",
+		0x108: "BTN_8",
+		0x109: "BTN_9",
+		0x110: "BTN_LEFT",
+		0x111: "BTN_RIGHT",
+		0x112: "BTN_MIDDLE",
+		0x113: "BTN_SIDE",
+		0x114: "BTN_EXTRA",
+		0x115: "BTN_FORWARD",
+		0x116: "BTN_BACK",
+		0x117: "BTN_TASK",
+		0x120: "BTN_TRIGGER",
+		0x121: "BTN_THUMB",
+		0x122: "BTN_THUMB2",
+		0x123: "BTN_TOP",
+		0x124: "BTN_TOP2",
+		0x125: "BTN_PINKIE",
+		0x126: "BTN_BASE",
+		0x127: "BTN_BASE2",
+		0x128: "BTN_BASE3",
+		0x129: "BTN_BASE4",
+		0x12a: "BTN_BASE5",
+		0x12b: "BTN_BASE6",
+		0x12f: "BTN_DEAD",
+		0x130: "BTN_SOUTH",
+		0x131: "BTN_EAST",
+		0x132: "BTN_C",
+		0x133: "BTN_NORTH",
+		0x134: "BTN_WEST",
+		0x135: "BTN_Z",
+		0x136: "BTN_TL",
+		0x137: "BTN_TR",
+		0x138: "BTN_TL2",
+		0x139: "BTN_TR2",
+		0x13a: "BTN_SELECT",
+		0x13b: "BTN_START",
+		0x13c: "BTN_MODE",
+		0x13d: "BTN_THUMBL",
+		0x13e: "BTN_THUMBR",
+		0x140: "BTN_TOOL_PEN",
+		0x141: "BTN_TOOL_RUBBER",
+		0x142: "BTN_TOOL_BRUSH",
+		0x143: "BTN_TOOL_PENCIL",
+		0x144: "BTN_TOOL_AIRBRUSH",
+		0x145: "BTN_TOOL_FINGER",
+		0x146: "BTN_TOOL_MOUSE",
+		0x147: "BTN_TOOL_LENS",
+		0x148: "BTN_TOOL_QUINTTAP",
+		0x149: "BTN_STYLUS3",
+		0x14a: "BTN_TOUCH",
+		0x14b: "BTN_STYLUS",
+		0x14c: "BTN_STYLUS2",
+		0x14d: "BTN_TOOL_DOUBLETAP",
+		0x14e: "BTN_TOOL_TRIPLETAP",
+		0x14f: "BTN_TOOL_QUADTAP",
+		0x150: "BTN_GEAR_DOWN",
+		0x151: "BTN_GEAR_UP",
+		0x160: "KEY_OK",
+		0x161: "KEY_SELECT",
+		0x162: "KEY_GOTO",
+		0x163: "KEY_CLEAR",
+		0x164: "KEY_POWER2",
+		0x165: "KEY_OPTION",
+		0x166: "KEY_INFO",
+		0x167: "KEY_TIME",
+		0x168: "KEY_VENDOR",
+		0x169: "KEY_ARCHIVE",
+		0x16a: "KEY_PROGRAM",
+		0x16b: "KEY_CHANNEL",
+		0x16c: "KEY_FAVORITES",
+		0x16d: "KEY_EPG",
+		0x16e: "KEY_PVR",
+		0x16f: "KEY_MHP",
+		0x170: "KEY_LANGUAGE",
+		0x171: "KEY_TITLE",
+		0x172: "KEY_SUBTITLE",
+		0x173: "KEY_ANGLE",
+		0x174: "KEY_FULL_SCREEN",
+		0x175: "KEY_MODE",
+		0x176: "KEY_KEYBOARD",
+		0x177: "KEY_ASPECT_RATIO",
+		0x178: "KEY_PC",
+		0x179: "KEY_TV",
+		0x17a: "KEY_TV2",
+		0x17b: "KEY_VCR",
+		0x17c: "KEY_VCR2",
+		0x17d: "KEY_SAT",
+		0x17e: "KEY_SAT2",
+		0x17f: "KEY_CD",
+		0x180: "KEY_TAPE",
+		0x181: "KEY_RADIO",
+		0x182: "KEY_TUNER",
+		0x183: "KEY_PLAYER",
+		0x184: "KEY_TEXT",
+		0x185: "KEY_DVD",
+		0x186: "KEY_AUX",
+		0x187: "KEY_MP3",
+		0x188: "KEY_AUDIO",
+		0x189: "KEY_VIDEO",
+		0x18a: "KEY_DIRECTORY",
+		0x18b: "KEY_LIST",
+		0x18c: "KEY_MEMO",
+		0x18d: "KEY_CALENDAR",
+		0x18e: "KEY_RED",
+		0x18f: "KEY_GREEN",
+		0x190: "KEY_YELLOW",
+		0x191: "KEY_BLUE",
+		0x192: "KEY_CHANNELUP",
+		0x193: "KEY_CHANNELDOWN",
+		0x194: "KEY_FIRST",
+		0x195: "KEY_LAST",
+		0x196: "KEY_AB",
+		0x197: "KEY_NEXT",
+		0x198: "KEY_RESTART",
+		0x199: "KEY_SLOW",
+		0x19a: "KEY_SHUFFLE",
+		0x19b: "KEY_BREAK",
+		0x19c: "KEY_PREVIOUS",
+		0x19d: "KEY_DIGITS",
+		0x19e: "KEY_TEEN",
+		0x19f: "KEY_TWEN",
+		0x1a0: "KEY_VIDEOPHONE",
+		0x1a1: "KEY_GAMES",
+		0x1a2: "KEY_ZOOMIN",
+		0x1a3: "KEY_ZOOMOUT",
+		0x1a4: "KEY_ZOOMRESET",
+		0x1a5: "KEY_WORDPROCESSOR",
+		0x1a6: "KEY_EDITOR",
+		0x1a7: "KEY_SPREADSHEET",
+		0x1a8: "KEY_GRAPHICSEDITOR",
+		0x1a9: "KEY_PRESENTATION",
+		0x1aa: "KEY_DATABASE",
+		0x1ab: "KEY_NEWS",
+		0x1ac: "KEY_VOICEMAIL",
+		0x1ad: "KEY_ADDRESSBOOK",
+		0x1ae: "KEY_MESSENGER",
+		0x1af: "KEY_DISPLAYTOGGLE",
+		0x1b0: "KEY_SPELLCHECK",
+		0x1b1: "KEY_LOGOFF",
+		0x1b2: "KEY_DOLLAR",
+		0x1b3: "KEY_EURO",
+		0x1b4: "KEY_FRAMEBACK",
+		0x1b5: "KEY_FRAMEFORWARD",
+		0x1b6: "KEY_CONTEXT_MENU",
+		0x1b7: "KEY_MEDIA_REPEAT",
+		0x1b8: "KEY_10CHANNELSUP",
+		0x1b9: "KEY_10CHANNELSDOWN",
+		0x1ba: "KEY_IMAGES",
+		0x1bc: "KEY_NOTIFICATION_CENTER",
+		0x1bd: "KEY_PICKUP_PHONE",
+		0x1be: "KEY_HANGUP_PHONE",
+		0x1bf: "KEY_LINK_PHONE",
+		0x1c0: "KEY_DEL_EOL",
+		0x1c1: "KEY_DEL_EOS",
+		0x1c2: "KEY_INS_LINE",
+		0x1c3: "KEY_DEL_LINE",
+		0x1d0: "KEY_FN",
+		0x1d1: "KEY_FN_ESC",
+		0x1d2: "KEY_FN_F1",
+		0x1d3: "KEY_FN_F2",
+		0x1d4: "KEY_FN_F3",
+		0x1d5: "KEY_FN_F4",
+		0x1d6: "KEY_FN_F5",
+		0x1d7: "KEY_FN_F6",
+		0x1d8: "KEY_FN_F7",
+		0x1d9: "KEY_FN_F8",
+		0x1da: "KEY_FN_F9",
+		0x1db: "KEY_FN_F10",
+		0x1dc: "KEY_FN_F11",
+		0x1dd: "KEY_FN_F12",
+		0x1de: "KEY_FN_1",
+		0x1df: "KEY_FN_2",
+		0x1e0: "KEY_FN_D",
+		0x1e1: "KEY_FN_E",
+		0x1e2: "KEY_FN_F",
+		0x1e3: "KEY_FN_S",
+		0x1e4: "KEY_FN_B",
+		0x1e5: "KEY_FN_RIGHT_SHIFT",
+		0x1f1: "KEY_BRL_DOT1",
+		0x1f2: "KEY_BRL_DOT2",
+		0x1f3: "KEY_BRL_DOT3",
+		0x1f4: "KEY_BRL_DOT4",
+		0x1f5: "KEY_BRL_DOT5",
+		0x1f6: "KEY_BRL_DOT6",
+		0x1f7: "KEY_BRL_DOT7",
+		0x1f8: "KEY_BRL_DOT8",
+		0x1f9: "KEY_BRL_DOT9",
+		0x1fa: "KEY_BRL_DOT10",
+		0x200: "KEY_NUMERIC_0",
+		0x201: "KEY_NUMERIC_1",
+		0x202: "KEY_NUMERIC_2",
+		0x203: "KEY_NUMERIC_3",
+		0x204: "KEY_NUMERIC_4",
+		0x205: "KEY_NUMERIC_5",
+		0x206: "KEY_NUMERIC_6",
+		0x207: "KEY_NUMERIC_7",
+		0x208: "KEY_NUMERIC_8",
+		0x209: "KEY_NUMERIC_9",
+		0x20a: "KEY_NUMERIC_STAR",
+		0x20b: "KEY_NUMERIC_POUND",
+		0x20c: "KEY_NUMERIC_A",
+		0x20d: "KEY_NUMERIC_B",
+		0x20e: "KEY_NUMERIC_C",
+		0x20f: "KEY_NUMERIC_D",
+		0x210: "KEY_CAMERA_FOCUS",
+		0x211: "KEY_WPS_BUTTON",
+		0x212: "KEY_TOUCHPAD_TOGGLE",
+		0x213: "KEY_TOUCHPAD_ON",
+		0x214: "KEY_TOUCHPAD_OFF",
+		0x215: "KEY_CAMERA_ZOOMIN",
+		0x216: "KEY_CAMERA_ZOOMOUT",
+		0x217: "KEY_CAMERA_UP",
+		0x218: "KEY_CAMERA_DOWN",
+		0x219: "KEY_CAMERA_LEFT",
+		0x21a: "KEY_CAMERA_RIGHT",
+		0x21b: "KEY_ATTENDANT_ON",
+		0x21c: "KEY_ATTENDANT_OFF",
+		0x21d: "KEY_ATTENDANT_TOGGLE",
+		0x21e: "KEY_LIGHTS_TOGGLE",
+		0x220: "BTN_DPAD_UP",
+		0x221: "BTN_DPAD_DOWN",
+		0x222: "BTN_DPAD_LEFT",
+		0x223: "BTN_DPAD_RIGHT",
+		0x230: "KEY_ALS_TOGGLE",
+		0x231: "KEY_ROTATE_LOCK_TOGGLE",
+		0x232: "KEY_REFRESH_RATE_TOGGLE",
+		0x240: "KEY_BUTTONCONFIG",
+		0x241: "KEY_TASKMANAGER",
+		0x242: "KEY_JOURNAL",
+		0x243: "KEY_CONTROLPANEL",
+		0x244: "KEY_APPSELECT",
+		0x245: "KEY_SCREENSAVER",
+		0x246: "KEY_VOICECOMMAND",
+		0x247: "KEY_ASSISTANT",
+		0x248: "KEY_KBD_LAYOUT_NEXT",
+		0x249: "KEY_EMOJI_PICKER",
+		0x24a: "KEY_DICTATE",
+		0x250: "KEY_BRIGHTNESS_MIN",
+		0x260: "KEY_KBDINPUTASSIST_PREV",
+		0x261: "KEY_KBDINPUTASSIST_NEXT",
+		0x262: "KEY_KBDINPUTASSIST_PREVGROUP",
+		0x263: "KEY_KBDINPUTASSIST_NEXTGROUP",
+		0x264: "KEY_KBDINPUTASSIST_ACCEPT",
+		0x265: "KEY_KBDINPUTASSIST_CANCEL",
+		0x266: "KEY_RIGHT_UP",
+		0x267: "KEY_RIGHT_DOWN",
+		0x268: "KEY_LEFT_UP",
+		0x269: "KEY_LEFT_DOWN",
+		0x26a: "KEY_ROOT_MENU",
+		0x26b: "KEY_MEDIA_TOP_MENU",
+		0x26c: "KEY_NUMERIC_11",
+		0x26d: "KEY_NUMERIC_12",
+		0x26e: "KEY_AUDIO_DESC",
+		0x26f: "KEY_3D_MODE",
+		0x270: "KEY_NEXT_FAVORITE",
+		0x271: "KEY_STOP_RECORD",
+		0x272: "KEY_PAUSE_RECORD",
+		0x273: "KEY_VOD",
+		0x274: "KEY_UNMUTE",
+		0x275: "KEY_FASTREVERSE",
+		0x276: "KEY_SLOWREVERSE",
+		0x277: "KEY_DATA",
+		0x278: "KEY_ONSCREEN_KEYBOARD",
+		0x279: "KEY_PRIVACY_SCREEN_TOGGLE",
+		0x27a: "KEY_SELECTIVE_SCREENSHOT",
+		0x27b: "KEY_NEXT_ELEMENT",
+		0x27c: "KEY_PREVIOUS_ELEMENT",
+		0x27d: "KEY_AUTOPILOT_ENGAGE_TOGGLE",
+		0x27e: "KEY_MARK_WAYPOINT",
+		0x27f: "KEY_SOS",
+		0x280: "KEY_NAV_CHART",
+		0x281: "KEY_FISHING_CHART",
+		0x282: "KEY_SINGLE_RANGE_RADAR",
+		0x283: "KEY_DUAL_RANGE_RADAR",
+		0x284: "KEY_RADAR_OVERLAY",
+		0x285: "KEY_TRADITIONAL_SONAR",
+		0x286: "KEY_CLEARVU_SONAR",
+		0x287: "KEY_SIDEVU_SONAR",
+		0x288: "KEY_NAV_INFO",
+		0x289: "KEY_BRIGHTNESS_MENU",
+		0x290: "KEY_MACRO1",
+		0x291: "KEY_MACRO2",
+		0x292: "KEY_MACRO3",
+		0x293: "KEY_MACRO4",
+		0x294: "KEY_MACRO5",
+		0x295: "KEY_MACRO6",
+		0x296: "KEY_MACRO7",
+		0x297: "KEY_MACRO8",
+		0x298: "KEY_MACRO9",
+		0x299: "KEY_MACRO10",
+		0x29a: "KEY_MACRO11",
+		0x29b: "KEY_MACRO12",
+		0x29c: "KEY_MACRO13",
+		0x29d: "KEY_MACRO14",
+		0x29e: "KEY_MACRO15",
+		0x29f: "KEY_MACRO16",
+		0x2a0: "KEY_MACRO17",
+		0x2a1: "KEY_MACRO18",
+		0x2a2: "KEY_MACRO19",
+		0x2a3: "KEY_MACRO20",
+		0x2a4: "KEY_MACRO21",
+		0x2a5: "KEY_MACRO22",
+		0x2a6: "KEY_MACRO23",
+		0x2a7: "KEY_MACRO24",
+		0x2a8: "KEY_MACRO25",
+		0x2a9: "KEY_MACRO26",
+		0x2aa: "KEY_MACRO27",
+		0x2ab: "KEY_MACRO28",
+		0x2ac: "KEY_MACRO29",
+		0x2ad: "KEY_MACRO30",
+		0x2b0: "KEY_MACRO_RECORD_START",
+		0x2b1: "KEY_MACRO_RECORD_STOP",
+		0x2b2: "KEY_MACRO_PRESET_CYCLE",
+		0x2b3: "KEY_MACRO_PRESET1",
+		0x2b4: "KEY_MACRO_PRESET2",
+		0x2b5: "KEY_MACRO_PRESET3",
+		0x2b8: "KEY_KBD_LCD_MENU1",
+		0x2b9: "KEY_KBD_LCD_MENU2",
+		0x2ba: "KEY_KBD_LCD_MENU3",
+		0x2bb: "KEY_KBD_LCD_MENU4",
+		0x2bc: "KEY_KBD_LCD_MENU5",
+		0x2c0: "BTN_TRIGGER_HAPPY1",
+		0x2c1: "BTN_TRIGGER_HAPPY2",
+		0x2c2: "BTN_TRIGGER_HAPPY3",
+		0x2c3: "BTN_TRIGGER_HAPPY4",
+		0x2c4: "BTN_TRIGGER_HAPPY5",
+		0x2c5: "BTN_TRIGGER_HAPPY6",
+		0x2c6: "BTN_TRIGGER_HAPPY7",
+		0x2c7: "BTN_TRIGGER_HAPPY8",
+		0x2c8: "BTN_TRIGGER_HAPPY9",
+		0x2c9: "BTN_TRIGGER_HAPPY10",
+		0x2ca: "BTN_TRIGGER_HAPPY11",
+		0x2cb: "BTN_TRIGGER_HAPPY12",
+		0x2cc: "BTN_TRIGGER_HAPPY13",
+		0x2cd: "BTN_TRIGGER_HAPPY14",
+		0x2ce: "BTN_TRIGGER_HAPPY15",
+		0x2cf: "BTN_TRIGGER_HAPPY16",
+		0x2d0: "BTN_TRIGGER_HAPPY17",
+		0x2d1: "BTN_TRIGGER_HAPPY18",
+		0x2d2: "BTN_TRIGGER_HAPPY19",
+		0x2d3: "BTN_TRIGGER_HAPPY20",
+		0x2d4: "BTN_TRIGGER_HAPPY21",
+		0x2d5: "BTN_TRIGGER_HAPPY22",
+		0x2d6: "BTN_TRIGGER_HAPPY23",
+		0x2d7: "BTN_TRIGGER_HAPPY24",
+		0x2d8: "BTN_TRIGGER_HAPPY25",
+		0x2d9: "BTN_TRIGGER_HAPPY26",
+		0x2da: "BTN_TRIGGER_HAPPY27",
+		0x2db: "BTN_TRIGGER_HAPPY28",
+		0x2dc: "BTN_TRIGGER_HAPPY29",
+		0x2dd: "BTN_TRIGGER_HAPPY30",
+		0x2de: "BTN_TRIGGER_HAPPY31",
+		0x2df: "BTN_TRIGGER_HAPPY32",
+		0x2e0: "BTN_TRIGGER_HAPPY33",
+		0x2e1: "BTN_TRIGGER_HAPPY34",
+		0x2e2: "BTN_TRIGGER_HAPPY35",
+		0x2e3: "BTN_TRIGGER_HAPPY36",
+		0x2e4: "BTN_TRIGGER_HAPPY37",
+		0x2e5: "BTN_TRIGGER_HAPPY38",
+		0x2e6: "BTN_TRIGGER_HAPPY39",
+		0x2e7: "BTN_TRIGGER_HAPPY40",
+	},
+	EV_REL: {
+		0x000: "REL_X",
+		0x001: "REL_Y",
+		0x002: "REL_Z",
+		0x003: "REL_RX",
+		0x004: "REL_RY",
+		0x005: "REL_RZ",
+		0x006: "REL_HWHEEL",
+		0x007: "REL_DIAL",
+		0x008: "REL_WHEEL",
+		0x009: "REL_MISC",
+		0x00a: "REL_RESERVED",
+		0x00b: "REL_WHEEL_HI_RES",
+		0x00c: "REL_HWHEEL_HI_RES",
+	},
+	EV_ABS: {
+		0x000: "ABS_X",
+		0x001: "ABS_Y",
+		0x002: "ABS_Z",
+		0x003: "ABS_RX",
+		0x004: "ABS_RY",
+		0x005: "ABS_RZ",
+		0x006: "ABS_THROTTLE",
+		0x007: "ABS_RUDDER",
+		0x008: "ABS_WHEEL",
+		0x009: "ABS_GAS",
+		0x00a: "ABS_BRAKE",
+		0x010: "ABS_HAT0X",
+		0x011: "ABS_HAT0Y",
+		0x012: "ABS_HAT1X",
+		0x013: "ABS_HAT1Y",
+		0x014: "ABS_HAT2X",
+		0x015: "ABS_HAT2Y",
+		0x016: "ABS_HAT3X",
+		0x017: "ABS_HAT3Y",
+		0x018: "ABS_PRESSURE",
+		0x019: "ABS_DISTANCE",
+		0x01a: "ABS_TILT_X",
+		0x01b: "ABS_TILT_Y",
+		0x01c: "ABS_TOOL_WIDTH",
+		0x020: "ABS_VOLUME",
+		0x021: "ABS_PROFILE",
+		0x028: "ABS_MISC",
+		0x02e: "ABS_RESERVED",
+		0x02f: "ABS_MT_SLOT",
+		0x030: "ABS_MT_TOUCH_MAJOR",
+		0x031: "ABS_MT_TOUCH_MINOR",
+		0x032: "ABS_MT_WIDTH_MAJOR",
+		0x033: "ABS_MT_WIDTH_MINOR",
+		0x034: "ABS_MT_ORIENTATION",
+		0x035: "ABS_MT_POSITION_X",
+		0x036: "ABS_MT_POSITION_Y",
+		0x037: "ABS_MT_TOOL_TYPE",
+		0x038: "ABS_MT_BLOB_ID",
+		0x039: "ABS_MT_TRACKING_ID",
+		0x03a: "ABS_MT_PRESSURE",
+		0x03b: "ABS_MT_DISTANCE",
+		0x03c: "ABS_MT_TOOL_X",
+		0x03d: "ABS_MT_TOOL_Y",
+	},
+	EV_MSC: {
+		0x000: "MSC_SERIAL",
+		0x001: "MSC_PULSELED",
+		0x002: "MSC_GESTURE",
+		0x003: "MSC_RAW",
+		0x004: "MSC_SCAN",
+		0x005: "MSC_TIMESTAMP",
+	},
+	EV_SW: {
+		0x000: "SW_LID",
+		0x001: "SW_TABLET_MODE",
+		0x002: "SW_HEADPHONE_INSERT",
+		0x003: "SW_RFKILL_ALL",
+		0x004: "SW_MICROPHONE_INSERT",
+		0x005: "SW_DOCK",
+		0x006: "SW_LINEOUT_INSERT",
+		0x007: "SW_JACK_PHYSICAL_INSERT",
+		0x008: "SW_VIDEOOUT_INSERT",
+		0x009: "SW_CAMERA_LENS_COVER",
+		0x00a: "SW_KEYPAD_SLIDE",
+		0x00b: "SW_FRONT_PROXIMITY",
+		0x00c: "SW_ROTATE_LOCK",
+		0x00d: "SW_LINEIN_INSERT",
+		0x00e: "SW_MUTE_DEVICE",
+		0x00f: "SW_PEN_INSERTED",
+		0x010: "SW_MACHINE_COVER",
+	},
+	EV_LED: {
+		0x000: "LED_NUML",
+		0x001: "LED_CAPSL",
+		0x002: "LED_SCROLLL",
+		0x003: "LED_COMPOSE",
+		0x004: "LED_KANA",
+		0x005: "LED_SLEEP",
+		0x006: "LED_SUSPEND",
+		0x007: "LED_MUTE",
+		0x008: "LED_MISC",
+		0x009: "LED_MAIL",
+		0x00a: "LED_CHARGING",
+	},
+	EV_SND: {
+		0x000: "SND_CLICK",
+		0x001: "SND_BELL",
+		0x002: "SND_TONE",
+	},
+	EV_REP: {
+		0x000: "REP_DELAY",
+		0x001: "REP_PERIOD",
+	},
+	EV_FF: {
+		0x050: "FF_RUMBLE",
+		0x051: "FF_PERIODIC",
+		0x052: "FF_CONSTANT",
+		0x053: "FF_SPRING",
+		0x054: "FF_FRICTION",
+		0x055: "FF_DAMPER",
+		0x056: "FF_INERTIA",
+		0x057: "FF_RAMP",
+		0x058: "FF_SQUARE",
+		0x059: "FF_TRIANGLE",
+		0x05a: "FF_SINE",
+		0x05b: "FF_SAW_UP",
+		0x05c: "FF_SAW_DOWN",
+		0x05d: "FF_CUSTOM",
+		0x060: "FF_GAIN",
+		0x061: "FF_AUTOCENTER",
+	},
+}
+
+var namedCodes = map[string]Code{
+	"SYN_REPORT":                   {EV_SYN, 0x000},
+	"SYN_CONFIG":                   {EV_SYN, 0x001},
+	"SYN_MT_REPORT":                {EV_SYN, 0x002},
+	"SYN_DROPPED":                  {EV_SYN, 0x003},
+	"KEY_RESERVED":                 {EV_KEY, 0x000},
+	"KEY_ESC":                      {EV_KEY, 0x001},
+	"KEY_1":                        {EV_KEY, 0x002},
+	"KEY_2":                        {EV_KEY, 0x003},
+	"KEY_3":                        {EV_KEY, 0x004},
+	"KEY_4":                        {EV_KEY, 0x005},
+	"KEY_5":                        {EV_KEY, 0x006},
+	"KEY_6":                        {EV_KEY, 0x007},
+	"KEY_7":                        {EV_KEY, 0x008},
+	"KEY_8":                        {EV_KEY, 0x009},
+	"KEY_9":                        {EV_KEY, 0x00a},
+	"KEY_0":                        {EV_KEY, 0x00b},
+	"KEY_MINUS":                    {EV_KEY, 0x00c},
+	"KEY_EQUAL":                    {EV_KEY, 0x00d},
+	"KEY_BACKSPACE":                {EV_KEY, 0x00e},
+	"KEY_TAB":                      {EV_KEY, 0x00f},
+	"KEY_Q":                        {EV_KEY, 0x010},
+	"KEY_W":                        {EV_KEY, 0x011},
+	"KEY_E":                        {EV_KEY, 0x012},
+	"KEY_R":                        {EV_KEY, 0x013},
+	"KEY_T":                        {EV_KEY, 0x014},
+	"KEY_Y":                        {EV_KEY, 0x015},
+	"KEY_U":                        {EV_KEY, 0x016},
+	"KEY_I":                        {EV_KEY, 0x017},
+	"KEY_O":                        {EV_KEY, 0x018},
+	"KEY_P":                        {EV_KEY, 0x019},
+	"KEY_LEFTBRACE":                {EV_KEY, 0x01a},
+	"KEY_RIGHTBRACE":               {EV_KEY, 0x01b},
+	"KEY_ENTER":                    {EV_KEY, 0x01c},
+	"KEY_LEFTCTRL":                 {EV_KEY, 0x01d},
+	"KEY_A":                        {EV_KEY, 0x01e},
+	"KEY_S":                        {EV_KEY, 0x01f},
+	"KEY_D":                        {EV_KEY, 0x020},
+	"KEY_F":                        {EV_KEY, 0x021},
+	"KEY_G":                        {EV_KEY, 0x022},
+	"KEY_H":                        {EV_KEY, 0x023},
+	"KEY_J":                        {EV_KEY, 0x024},
+	"KEY_K":                        {EV_KEY, 0x025},
+	"KEY_L":                        {EV_KEY, 0x026},
+	"KEY_SEMICOLON":                {EV_KEY, 0x027},
+	"KEY_APOSTROPHE":               {EV_KEY, 0x028},
+	"KEY_GRAVE":                    {EV_KEY, 0x029},
+	"KEY_LEFTSHIFT":                {EV_KEY, 0x02a},
+	"KEY_BACKSLASH":                {EV_KEY, 0x02b},
+	"KEY_Z":                        {EV_KEY, 0x02c},
+	"KEY_X":                        {EV_KEY, 0x02d},
+	"KEY_C":                        {EV_KEY, 0x02e},
+	"KEY_V":                        {EV_KEY, 0x02f},
+	"KEY_B":                        {EV_KEY, 0x030},
+	"KEY_N":                        {EV_KEY, 0x031},
+	"KEY_M":                        {EV_KEY, 0x032},
+	"KEY_COMMA":                    {EV_KEY, 0x033},
+	"KEY_DOT":                      {EV_KEY, 0x034},
+	"KEY_SLASH":                    {EV_KEY, 0x035},
+	"KEY_RIGHTSHIFT":               {EV_KEY, 0x036},
+	"KEY_KPASTERISK":               {EV_KEY, 0x037},
+	"KEY_LEFTALT":                  {EV_KEY, 0x038},
+	"KEY_SPACE":                    {EV_KEY, 0x039},
+	"KEY_CAPSLOCK":                 {EV_KEY, 0x03a},
+	"KEY_F1":                       {EV_KEY, 0x03b},
+	"KEY_F2":                       {EV_KEY, 0x03c},
+	"KEY_F3":                       {EV_KEY, 0x03d},
+	"KEY_F4":                       {EV_KEY, 0x03e},
+	"KEY_F5":                       {EV_KEY, 0x03f},
+	"KEY_F6":                       {EV_KEY, 0x040},
+	"KEY_F7":                       {EV_KEY, 0x041},
+	"KEY_F8":                       {EV_KEY, 0x042},
+	"KEY_F9":                       {EV_KEY, 0x043},
+	"KEY_F10":                      {EV_KEY, 0x044},
+	"KEY_NUMLOCK":                  {EV_KEY, 0x045},
+	"KEY_SCROLLLOCK":               {EV_KEY, 0x046},
+	"KEY_KP7":                      {EV_KEY, 0x047},
+	"KEY_KP8":                      {EV_KEY, 0x048},
+	"KEY_KP9":                      {EV_KEY, 0x049},
+	"KEY_KPMINUS":                  {EV_KEY, 0x04a},
+	"KEY_KP4":                      {EV_KEY, 0x04b},
+	"KEY_KP5":                      {EV_KEY, 0x04c},
+	"KEY_KP6":                      {EV_KEY, 0x04d},
+	"KEY_KPPLUS":                   {EV_KEY, 0x04e},
+	"KEY_KP1":                      {EV_KEY, 0x04f},
+	"KEY_KP2":                      {EV_KEY, 0x050},
+	"KEY_KP3":                      {EV_KEY, 0x051},
+	"KEY_KP0":                      {EV_KEY, 0x052},
+	"KEY_KPDOT":                    {EV_KEY, 0x053},
+	"KEY_ZENKAKUHANKAKU":           {EV_KEY, 0x055},
+	"KEY_102ND":                    {EV_KEY, 0x056},
+	"KEY_F11":                      {EV_KEY, 0x057},
+	"KEY_F12":                      {EV_KEY, 0x058},
+	"KEY_RO":                       {EV_KEY, 0x059},
+	"KEY_KATAKANA":                 {EV_KEY, 0x05a},
+	"KEY_HIRAGANA":                 {EV_KEY, 0x05b},
+	"KEY_HENKAN":                   {EV_KEY, 0x05c},
+	"KEY_KATAKANAHIRAGANA":         {EV_KEY, 0x05d},
+	"KEY_MUHENKAN":                 {EV_KEY, 0x05e},
+	"KEY_KPJPCOMMA":                {EV_KEY, 0x05f},
+	"KEY_KPENTER":                  {EV_KEY, 0x060},
+	"KEY_RIGHTCTRL":                {EV_KEY, 0x061},
+	"KEY_KPSLASH":                  {EV_KEY, 0x062},
+	"KEY_SYSRQ":                    {EV_KEY, 0x063},
+	"KEY_RIGHTALT":                 {EV_KEY, 0x064},
+	"KEY_LINEFEED":                 {EV_KEY, 0x065},
+	"KEY_HOME":                     {EV_KEY, 0x066},
+	"KEY_UP":                       {EV_KEY, 0x067},
+	"KEY_PAGEUP":                   {EV_KEY, 0x068},
+	"KEY_LEFT":                     {EV_KEY, 0x069},
+	"KEY_RIGHT":                    {EV_KEY, 0x06a},
+	"KEY_END":                      {EV_KEY, 0x06b},
+	"KEY_DOWN":                     {EV_KEY, 0x06c},
+	"KEY_PAGEDOWN":                 {EV_KEY, 0x06d},
+	"KEY_INSERT":                   {EV_KEY, 0x06e},
+	"KEY_DELETE":                   {EV_KEY, 0x06f},
+	"KEY_MACRO":                    {EV_KEY, 0x070},
+	"KEY_MUTE":                     {EV_KEY, 0x071},
+	"KEY_VOLUMEDOWN":               {EV_KEY, 0x072},
+	"KEY_VOLUMEUP":                 {EV_KEY, 0x073},
+	"KEY_POWER":                    {EV_KEY, 0x074},
+	"KEY_KPEQUAL":                  {EV_KEY, 0x075},
+	"KEY_KPPLUSMINUS":              {EV_KEY, 0x076},
+	"KEY_PAUSE":                    {EV_KEY, 0x077},
+	"KEY_SCALE":                    {EV_KEY, 0x078},
+	"KEY_KPCOMMA":                  {EV_KEY, 0x079},
+	"KEY_HANGEUL":                  {EV_KEY, 0x07a},
+	"KEY_HANGUEL":                  {EV_KEY, 0x07a},
+	"KEY_HANJA":                    {EV_KEY, 0x07b},
+	"KEY_YEN":                      {EV_KEY, 0x07c},
+	"KEY_LEFTMETA":                 {EV_KEY, 0x07d},
+	"KEY_RIGHTMETA":                {EV_KEY, 0x07e},
+	"KEY_COMPOSE":                  {EV_KEY, 0x07f},
+	"KEY_STOP":                     {EV_KEY, 0x080},
+	"KEY_AGAIN":                    {EV_KEY, 0x081},
+	"KEY_PROPS":                    {EV_KEY, 0x082},
+	"KEY_UNDO":                     {EV_KEY, 0x083},
+	"KEY_FRONT":                    {EV_KEY, 0x084},
+	"KEY_COPY":                     {EV_KEY, 0x085},
+	"KEY_OPEN":                     {EV_KEY, 0x086},
+	"KEY_PASTE":                    {EV_KEY, 0x087},
+	"KEY_FIND":                     {EV_KEY, 0x088},
+	"KEY_CUT":                      {EV_KEY, 0x089},
+	"KEY_HELP":                     {EV_KEY, 0x08a},
+	"KEY_MENU":                     {EV_KEY, 0x08b},
+	"KEY_CALC":                     {EV_KEY, 0x08c},
+	"KEY_SETUP":                    {EV_KEY, 0x08d},
+	"KEY_SLEEP":                    {EV_KEY, 0x08e},
+	"KEY_WAKEUP":                   {EV_KEY, 0x08f},
+	"KEY_FILE":                     {EV_KEY, 0x090},
+	"KEY_SENDFILE":                 {EV_KEY, 0x091},
+	"KEY_DELETEFILE":               {EV_KEY, 0x092},
+	"KEY_XFER":                     {EV_KEY, 0x093},
+	"KEY_PROG1":                    {EV_KEY, 0x094},
+	"KEY_PROG2":                    {EV_KEY, 0x095},
+	"KEY_WWW":                      {EV_KEY, 0x096},
+	"KEY_MSDOS":                    {EV_KEY, 0x097},
+	"KEY_COFFEE":                   {EV_KEY, 0x098},
+	"KEY_SCREENLOCK":               {EV_KEY, 0x098},
+	"KEY_ROTATE_DISPLAY":           {EV_KEY, 0x099},
+	"KEY_DIRECTION":                {EV_KEY, 0x099},
+	"KEY_CYCLEWINDOWS":             {EV_KEY, 0x09a},
+	"KEY_MAIL":                     {EV_KEY, 0x09b},
+	"KEY_BOOKMARKS":                {EV_KEY, 0x09c},
+	"KEY_COMPUTER":                 {EV_KEY, 0x09d},
+	"KEY_BACK":                     {EV_KEY, 0x09e},
+	"KEY_FORWARD":                  {EV_KEY, 0x09f},
+	"KEY_CLOSECD":                  {EV_KEY, 0x0a0},
+	"KEY_EJECTCD":                  {EV_KEY, 0x0a1},
+	"KEY_EJECTCLOSECD":             {EV_KEY, 0x0a2},
+	"KEY_NEXTSONG":                 {EV_KEY, 0x0a3},
+	"KEY_PLAYPAUSE":                {EV_KEY, 0x0a4},
+	"KEY_PREVIOUSSONG":             {EV_KEY, 0x0a5},
+	"KEY_STOPCD":                   {EV_KEY, 0x0a6},
+	"KEY_RECORD":                   {EV_KEY, 0x0a7},
+	"KEY_REWIND":                   {EV_KEY, 0x0a8},
+	"KEY_PHONE":                    {EV_KEY, 0x0a9},
+	"KEY_ISO":                      {EV_KEY, 0x0aa},
+	"KEY_CONFIG":                   {EV_KEY, 0x0ab},
+	"KEY_HOMEPAGE":                 {EV_KEY, 0x0ac},
+	"KEY_REFRESH":                  {EV_KEY, 0x0ad},
+	"KEY_EXIT":                     {EV_KEY, 0x0ae},
+	"KEY_MOVE":                     {EV_KEY, 0x0af},
+	"KEY_EDIT":                     {EV_KEY, 0x0b0},
+	"KEY_SCROLLUP":                 {EV_KEY, 0x0b1},
+	"KEY_SCROLLDOWN":               {EV_KEY, 0x0b2},
+	"KEY_KPLEFTPAREN":              {EV_KEY, 0x0b3},
+	"KEY_KPRIGHTPAREN":             {EV_KEY, 0x0b4},
+	"KEY_NEW":                      {EV_KEY, 0x0b5},
+	"KEY_REDO":                     {EV_KEY, 0x0b6},
+	"KEY_F13":                      {EV_KEY, 0x0b7},
+	"KEY_F14":                      {EV_KEY, 0x0b8},
+	"KEY_F15":                      {EV_KEY, 0x0b9},
+	"KEY_F16":                      {EV_KEY, 0x0ba},
+	"KEY_F17":                      {EV_KEY, 0x0bb},
+	"KEY_F18":                      {EV_KEY, 0x0bc},
+	"KEY_F19":                      {EV_KEY, 0x0bd},
+	"KEY_F20":                      {EV_KEY, 0x0be},
+	"KEY_F21":                      {EV_KEY, 0x0bf},
+	"KEY_F22":                      {EV_KEY, 0x0c0},
+	"KEY_F23":                      {EV_KEY, 0x0c1},
+	"KEY_F24":                      {EV_KEY, 0x0c2},
+	"KEY_PLAYCD":                   {EV_KEY, 0x0c8},
+	"KEY_PAUSECD":                  {EV_KEY, 0x0c9},
+	"KEY_PROG3":                    {EV_KEY, 0x0ca},
+	"KEY_PROG4":                    {EV_KEY, 0x0cb},
+	"KEY_ALL_APPLICATIONS":         {EV_KEY, 0x0cc},
+	"KEY_DASHBOARD":                {EV_KEY, 0x0cc},
+	"KEY_SUSPEND":                  {EV_KEY, 0x0cd},
+	"KEY_CLOSE":                    {EV_KEY, 0x0ce},
+	"KEY_PLAY":                     {EV_KEY, 0x0cf},
+	"KEY_FASTFORWARD":              {EV_KEY, 0x0d0},
+	"KEY_BASSBOOST":                {EV_KEY, 0x0d1},
+	"KEY_PRINT":                    {EV_KEY, 0x0d2},
+	"KEY_HP":                       {EV_KEY, 0x0d3},
+	"KEY_CAMERA":                   {EV_KEY, 0x0d4},
+	"KEY_SOUND":                    {EV_KEY, 0x0d5},
+	"KEY_QUESTION":                 {EV_KEY, 0x0d6},
+	"KEY_EMAIL":                    {EV_KEY, 0x0d7},
+	"KEY_CHAT":                     {EV_KEY, 0x0d8},
+	"KEY_SEARCH":                   {EV_KEY, 0x0d9},
+	"KEY_CONNECT":                  {EV_KEY, 0x0da},
+	"KEY_FINANCE":                  {EV_KEY, 0x0db},
+	"KEY_SPORT":                    {EV_KEY, 0x0dc},
+	"KEY_SHOP":                     {EV_KEY, 0x0dd},
+	"KEY_ALTERASE":                 {EV_KEY, 0x0de},
+	"KEY_CANCEL":                   {EV_KEY, 0x0df},
+	"KEY_BRIGHTNESSDOWN":           {EV_KEY, 0x0e0},
+	"KEY_BRIGHTNESSUP":             {EV_KEY, 0x0e1},
+	"KEY_MEDIA":                    {EV_KEY, 0x0e2},
+	"KEY_SWITCHVIDEOMODE":          {EV_KEY, 0x0e3},
+	"KEY_KBDILLUMTOGGLE":           {EV_KEY, 0x0e4},
+	"KEY_KBDILLUMDOWN":             {EV_KEY, 0x0e5},
+	"KEY_KBDILLUMUP":               {EV_KEY, 0x0e6},
+	"KEY_SEND":                     {EV_KEY, 0x0e7},
+	"KEY_REPLY":                    {EV_KEY, 0x0e8},
+	"KEY_FORWARDMAIL":              {EV_KEY, 0x0e9},
+	"KEY_SAVE":                     {EV_KEY, 0x0ea},
+	"KEY_DOCUMENTS":                {EV_KEY, 0x0eb},
+	"KEY_BATTERY":                  {EV_KEY, 0x0ec},
+	"KEY_BLUETOOTH":                {EV_KEY, 0x0ed},
+	"KEY_WLAN":                     {EV_KEY, 0x0ee},
+	"KEY_UWB":                      {EV_KEY, 0x0ef},
+	"KEY_UNKNOWN":                  {EV_KEY, 0x0f0},
+	"KEY_VIDEO_NEXT":               {EV_KEY, 0x0f1},
+	"KEY_VIDEO_PREV":               {EV_KEY, 0x0f2},
+	"KEY_BRIGHTNESS_CYCLE":         {EV_KEY, 0x0f3},
+	"KEY_BRIGHTNESS_AUTO":          {EV_KEY, 0x0f4},
+	"KEY_BRIGHTNESS_ZERO":          {EV_KEY, 0x0f4},
+	"KEY_DISPLAY_OFF":              {EV_KEY, 0x0f5},
+	"KEY_WWAN":                     {EV_KEY, 0x0f6},
+	"KEY_WIMAX":                    {EV_KEY, 0x0f6},
+	"KEY_RFKILL":                   {EV_KEY, 0x0f7},
+	"KEY_MICMUTE":                  {EV_KEY, 0x0f8},
+	"BTN_MISC":                     {EV_KEY, 0x100},
+	"BTN_0":                        {EV_KEY, 0x100},
+	"BTN_1":                        {EV_KEY, 0x101},
+	"BTN_2":                        {EV_KEY, 0x102},
+	"BTN_3":                        {EV_KEY, 0x103},
+	"BTN_4":                        {EV_KEY, 0x104},
+	"BTN_5":                        {EV_KEY, 0x105},
+	"BTN_6":                        {EV_KEY, 0x106},
+	"BTN_7":                        {EV_KEY, 0x107},
+	"BTN_8":                        {EV_KEY, 0x108},
+	"BTN_9":                        {EV_KEY, 0x109},
+	"BTN_MOUSE":                    {EV_KEY, 0x110},
+	"BTN_LEFT":                     {EV_KEY, 0x110},
+	"BTN_RIGHT":                    {EV_KEY, 0x111},
+	"BTN_MIDDLE":                   {EV_KEY, 0x112},
+	"BTN_SIDE":                     {EV_KEY, 0x113},
+	"BTN_EXTRA":                    {EV_KEY, 0x114},
+	"BTN_FORWARD":                  {EV_KEY, 0x115},
+	"BTN_BACK":                     {EV_KEY, 0x116},
+	"BTN_TASK":                     {EV_KEY, 0x117},
+	"BTN_JOYSTICK":                 {EV_KEY, 0x120},
+	"BTN_TRIGGER":                  {EV_KEY, 0x120},
+	"BTN_THUMB":                    {EV_KEY, 0x121},
+	"BTN_THUMB2":                   {EV_KEY, 0x122},
+	"BTN_TOP":                      {EV_KEY, 0x123},
+	"BTN_TOP2":                     {EV_KEY, 0x124},
+	"BTN_PINKIE":                   {EV_KEY, 0x125},
+	"BTN_BASE":                     {EV_KEY, 0x126},
+	"BTN_BASE2":                    {EV_KEY, 0x127},
+	"BTN_BASE3":                    {EV_KEY, 0x128},
+	"BTN_BASE4":                    {EV_KEY, 0x129},
+	"BTN_BASE5":                    {EV_KEY, 0x12a},
+	"BTN_BASE6":                    {EV_KEY, 0x12b},
+	"BTN_DEAD":                     {EV_KEY, 0x12f},
+	"BTN_GAMEPAD":                  {EV_KEY, 0x130},
+	"BTN_SOUTH":                    {EV_KEY, 0x130},
+	"BTN_A":                        {EV_KEY, 0x130},
+	"BTN_EAST":                     {EV_KEY, 0x131},
+	"BTN_B":                        {EV_KEY, 0x131},
+	"BTN_C":                        {EV_KEY, 0x132},
+	"BTN_NORTH":                    {EV_KEY, 0x133},
+	"BTN_X":                        {EV_KEY, 0x133},
+	"BTN_WEST":                     {EV_KEY, 0x134},
+	"BTN_Y":                        {EV_KEY, 0x134},
+	"BTN_Z":                        {EV_KEY, 0x135},
+	"BTN_TL":                       {EV_KEY, 0x136},
+	"BTN_TR":                       {EV_KEY, 0x137},
+	"BTN_TL2":                      {EV_KEY, 0x138},
+	"BTN_TR2":                      {EV_KEY, 0x139},
+	"BTN_SELECT":                   {EV_KEY, 0x13a},
+	"BTN_START":                    {EV_KEY, 0x13b},
+	"BTN_MODE":                     {EV_KEY, 0x13c},
+	"BTN_THUMBL":                   {EV_KEY, 0x13d},
+	"BTN_THUMBR":                   {EV_KEY, 0x13e},
+	"BTN_DIGI":                     {EV_KEY, 0x140},
+	"BTN_TOOL_PEN":                 {EV_KEY, 0x140},
+	"BTN_TOOL_RUBBER":              {EV_KEY, 0x141},
+	"BTN_TOOL_BRUSH":               {EV_KEY, 0x142},
+	"BTN_TOOL_PENCIL":              {EV_KEY, 0x143},
+	"BTN_TOOL_AIRBRUSH":            {EV_KEY, 0x144},
+	"BTN_TOOL_FINGER":              {EV_KEY, 0x145},
+	"BTN_TOOL_MOUSE":               {EV_KEY, 0x146},
+	"BTN_TOOL_LENS":                {EV_KEY, 0x147},
+	"BTN_TOOL_QUINTTAP":            {EV_KEY, 0x148},
+	"BTN_STYLUS3":                  {EV_KEY, 0x149},
+	"BTN_TOUCH":                    {EV_KEY, 0x14a},
+	"BTN_STYLUS":                   {EV_KEY, 0x14b},
+	"BTN_STYLUS2":                  {EV_KEY, 0x14c},
+	"BTN_TOOL_DOUBLETAP":           {EV_KEY, 0x14d},
+	"BTN_TOOL_TRIPLETAP":           {EV_KEY, 0x14e},
+	"BTN_TOOL_QUADTAP":             {EV_KEY, 0x14f},
+	"BTN_WHEEL":                    {EV_KEY, 0x150},
+	"BTN_GEAR_DOWN":                {EV_KEY, 0x150},
+	"BTN_GEAR_UP":                  {EV_KEY, 0x151},
+	"KEY_OK":                       {EV_KEY, 0x160},
+	"KEY_SELECT":                   {EV_KEY, 0x161},
+	"KEY_GOTO":                     {EV_KEY, 0x162},
+	"KEY_CLEAR":                    {EV_KEY, 0x163},
+	"KEY_POWER2":                   {EV_KEY, 0x164},
+	"KEY_OPTION":                   {EV_KEY, 0x165},
+	"KEY_INFO":                     {EV_KEY, 0x166},
+	"KEY_TIME":                     {EV_KEY, 0x167},
+	"KEY_VENDOR":                   {EV_KEY, 0x168},
+	"KEY_ARCHIVE":                  {EV_KEY, 0x169},
+	"KEY_PROGRAM":                  {EV_KEY, 0x16a},
+	"KEY_CHANNEL":                  {EV_KEY, 0x16b},
+	"KEY_FAVORITES":                {EV_KEY, 0x16c},
+	"KEY_EPG":                      {EV_KEY, 0x16d},
+	"KEY_PVR":                      {EV_KEY, 0x16e},
+	"KEY_MHP":                      {EV_KEY, 0x16f},
+	"KEY_LANGUAGE":                 {EV_KEY, 0x170},
+	"KEY_TITLE":                    {EV_KEY, 0x171},
+	"KEY_SUBTITLE":                 {EV_KEY, 0x172},
+	"KEY_ANGLE":                    {EV_KEY, 0x173},
+	"KEY_FULL_SCREEN":              {EV_KEY, 0x174},
+	"KEY_ZOOM":                     {EV_KEY, 0x174},
+	"KEY_MODE":                     {EV_KEY, 0x175},
+	"KEY_KEYBOARD":                 {EV_KEY, 0x176},
+	"KEY_ASPECT_RATIO":             {EV_KEY, 0x177},
+	"KEY_SCREEN":                   {EV_KEY, 0x177},
+	"KEY_PC":                       {EV_KEY, 0x178},
+	"KEY_TV":                       {EV_KEY, 0x179},
+	"KEY_TV2":                      {EV_KEY, 0x17a},
+	"KEY_VCR":                      {EV_KEY, 0x17b},
+	"KEY_VCR2":                     {EV_KEY, 0x17c},
+	"KEY_SAT":                      {EV_KEY, 0x17d},
+	"KEY_SAT2":                     {EV_KEY, 0x17e},
+	"KEY_CD":                       {EV_KEY, 0x17f},
+	"KEY_TAPE":                     {EV_KEY, 0x180},
+	"KEY_RADIO":                    {EV_KEY, 0x181},
+	"KEY_TUNER":                    {EV_KEY, 0x182},
+	"KEY_PLAYER":                   {EV_KEY, 0x183},
+	"KEY_TEXT":                     {EV_KEY, 0x184},
+	"KEY_DVD":                      {EV_KEY, 0x185},
+	"KEY_AUX":                      {EV_KEY, 0x186},
+	"KEY_MP3":                      {EV_KEY, 0x187},
+	"KEY_AUDIO":                    {EV_KEY, 0x188},
+	"KEY_VIDEO":                    {EV_KEY, 0x189},
+	"KEY_DIRECTORY":                {EV_KEY, 0x18a},
+	"KEY_LIST":                     {EV_KEY, 0x18b},
+	"KEY_MEMO":                     {EV_KEY, 0x18c},
+	"KEY_CALENDAR":                 {EV_KEY, 0x18d},
+	"KEY_RED":                      {EV_KEY, 0x18e},
+	"KEY_GREEN":                    {EV_KEY, 0x18f},
+	"KEY_YELLOW":                   {EV_KEY, 0x190},
+	"KEY_BLUE":                     {EV_KEY, 0x191},
+	"KEY_CHANNELUP":                {EV_KEY, 0x192},
+	"KEY_CHANNELDOWN":              {EV_KEY, 0x193},
+	"KEY_FIRST":                    {EV_KEY, 0x194},
+	"KEY_LAST":                     {EV_KEY, 0x195},
+	"KEY_AB":                       {EV_KEY, 0x196},
+	"KEY_NEXT":                     {EV_KEY, 0x197},
+	"KEY_RESTART":                  {EV_KEY, 0x198},
+	"KEY_SLOW":                     {EV_KEY, 0x199},
+	"KEY_SHUFFLE":                  {EV_KEY, 0x19a},
+	"KEY_BREAK":                    {EV_KEY, 0x19b},
+	"KEY_PREVIOUS":                 {EV_KEY, 0x19c},
+	"KEY_DIGITS":                   {EV_KEY, 0x19d},
+	"KEY_TEEN":                     {EV_KEY, 0x19e},
+	"KEY_TWEN":                     {EV_KEY, 0x19f},
+	"KEY_VIDEOPHONE":               {EV_KEY, 0x1a0},
+	"KEY_GAMES":                    {EV_KEY, 0x1a1},
+	"KEY_ZOOMIN":                   {EV_KEY, 0x1a2},
+	"KEY_ZOOMOUT":                  {EV_KEY, 0x1a3},
+	"KEY_ZOOMRESET":                {EV_KEY, 0x1a4},
+	"KEY_WORDPROCESSOR":            {EV_KEY, 0x1a5},
+	"KEY_EDITOR":                   {EV_KEY, 0x1a6},
+	"KEY_SPREADSHEET":              {EV_KEY, 0x1a7},
+	"KEY_GRAPHICSEDITOR":           {EV_KEY, 0x1a8},
+	"KEY_PRESENTATION":             {EV_KEY, 0x1a9},
+	"KEY_DATABASE":                 {EV_KEY, 0x1aa},
+	"KEY_NEWS":                     {EV_KEY, 0x1ab},
+	"KEY_VOICEMAIL":                {EV_KEY, 0x1ac},
+	"KEY_ADDRESSBOOK":              {EV_KEY, 0x1ad},
+	"KEY_MESSENGER":                {EV_KEY, 0x1ae},
+	"KEY_DISPLAYTOGGLE":            {EV_KEY, 0x1af},
+	"KEY_BRIGHTNESS_TOGGLE":        {EV_KEY, 0x1af},
+	"KEY_SPELLCHECK":               {EV_KEY, 0x1b0},
+	"KEY_LOGOFF":                   {EV_KEY, 0x1b1},
+	"KEY_DOLLAR":                   {EV_KEY, 0x1b2},
+	"KEY_EURO":                     {EV_KEY, 0x1b3},
+	"KEY_FRAMEBACK":                {EV_KEY, 0x1b4},
+	"KEY_FRAMEFORWARD":             {EV_KEY, 0x1b5},
+	"KEY_CONTEXT_MENU":             {EV_KEY, 0x1b6},
+	"KEY_MEDIA_REPEAT":             {EV_KEY, 0x1b7},
+	"KEY_10CHANNELSUP":             {EV_KEY, 0x1b8},
+	"KEY_10CHANNELSDOWN":           {EV_KEY, 0x1b9},
+	"KEY_IMAGES":                   {EV_KEY, 0x1ba},
+	"KEY_NOTIFICATION_CENTER":      {EV_KEY, 0x1bc},
+	"KEY_PICKUP_PHONE":             {EV_KEY, 0x1bd},
+	"KEY_HANGUP_PHONE":             {EV_KEY, 0x1be},
+	"KEY_LINK_PHONE":               {EV_KEY, 0x1bf},
+	"KEY_DEL_EOL":                  {EV_KEY, 0x1c0},
+	"KEY_DEL_EOS":                  {EV_KEY, 0x1c1},
+	"KEY_INS_LINE":                 {EV_KEY, 0x1c2},
+	"KEY_DEL_LINE":                 {EV_KEY, 0x1c3},
+	"KEY_FN":                       {EV_KEY, 0x1d0},
+	"KEY_FN_ESC":                   {EV_KEY, 0x1d1},
+	"KEY_FN_F1":                    {EV_KEY, 0x1d2},
+	"KEY_FN_F2":                    {EV_KEY, 0x1d3},
+	"KEY_FN_F3":                    {EV_KEY, 0x1d4},
+	"KEY_FN_F4":                    {EV_KEY, 0x1d5},
+	"KEY_FN_F5":                    {EV_KEY, 0x1d6},
+	"KEY_FN_F6":                    {EV_KEY, 0x1d7},
+	"KEY_FN_F7":                    {EV_KEY, 0x1d8},
+	"KEY_FN_F8":                    {EV_KEY, 0x1d9},
+	"KEY_FN_F9":                    {EV_KEY, 0x1da},
+	"KEY_FN_F10":                   {EV_KEY, 0x1db},
+	"KEY_FN_F11":                   {EV_KEY, 0x1dc},
+	"KEY_FN_F12":                   {EV_KEY, 0x1dd},
+	"KEY_FN_1":                     {EV_KEY, 0x1de},
+	"KEY_FN_2":                     {EV_KEY, 0x1df},
+	"KEY_FN_D":                     {EV_KEY, 0x1e0},
+	"KEY_FN_E":                     {EV_KEY, 0x1e1},
+	"KEY_FN_F":                     {EV_KEY, 0x1e2},
+	"KEY_FN_S":                     {EV_KEY, 0x1e3},
+	"KEY_FN_B":                     {EV_KEY, 0x1e4},
+	"KEY_FN_RIGHT_SHIFT":           {EV_KEY, 0x1e5},
+	"KEY_BRL_DOT1":                 {EV_KEY, 0x1f1},
+	"KEY_BRL_DOT2":                 {EV_KEY, 0x1f2},
+	"KEY_BRL_DOT3":                 {EV_KEY, 0x1f3},
+	"KEY_BRL_DOT4":                 {EV_KEY, 0x1f4},
+	"KEY_BRL_DOT5":                 {EV_KEY, 0x1f5},
+	"KEY_BRL_DOT6":                 {EV_KEY, 0x1f6},
+	"KEY_BRL_DOT7":                 {EV_KEY, 0x1f7},
+	"KEY_BRL_DOT8":                 {EV_KEY, 0x1f8},
+	"KEY_BRL_DOT9":                 {EV_KEY, 0x1f9},
+	"KEY_BRL_DOT10":                {EV_KEY, 0x1fa},
+	"KEY_NUMERIC_0":                {EV_KEY, 0x200},
+	"KEY_NUMERIC_1":                {EV_KEY, 0x201},
+	"KEY_NUMERIC_2":                {EV_KEY, 0x202},
+	"KEY_NUMERIC_3":                {EV_KEY, 0x203},
+	"KEY_NUMERIC_4":                {EV_KEY, 0x204},
+	"KEY_NUMERIC_5":                {EV_KEY, 0x205},
+	"KEY_NUMERIC_6":                {EV_KEY, 0x206},
+	"KEY_NUMERIC_7":                {EV_KEY, 0x207},
+	"KEY_NUMERIC_8":                {EV_KEY, 0x208},
+	"KEY_NUMERIC_9":                {EV_KEY, 0x209},
+	"KEY_NUMERIC_STAR":             {EV_KEY, 0x20a},
+	"KEY_NUMERIC_POUND":            {EV_KEY, 0x20b},
+	"KEY_NUMERIC_A":                {EV_KEY, 0x20c},
+	"KEY_NUMERIC_B":                {EV_KEY, 0x20d},
+	"KEY_NUMERIC_C":                {EV_KEY, 0x20e},
+	"KEY_NUMERIC_D":                {EV_KEY, 0x20f},
+	"KEY_CAMERA_FOCUS":             {EV_KEY, 0x210},
+	"KEY_WPS_BUTTON":               {EV_KEY, 0x211},
+	"KEY_TOUCHPAD_TOGGLE":          {EV_KEY, 0x212},
+	"KEY_TOUCHPAD_ON":              {EV_KEY, 0x213},
+	"KEY_TOUCHPAD_OFF":             {EV_KEY, 0x214},
+	"KEY_CAMERA_ZOOMIN":            {EV_KEY, 0x215},
+	"KEY_CAMERA_ZOOMOUT":           {EV_KEY, 0x216},
+	"KEY_CAMERA_UP":                {EV_KEY, 0x217},
+	"KEY_CAMERA_DOWN":              {EV_KEY, 0x218},
+	"KEY_CAMERA_LEFT":              {EV_KEY, 0x219},
+	"KEY_CAMERA_RIGHT":             {EV_KEY, 0x21a},
+	"KEY_ATTENDANT_ON":             {EV_KEY, 0x21b},
+	"KEY_ATTENDANT_OFF":            {EV_KEY, 0x21c},
+	"KEY_ATTENDANT_TOGGLE":         {EV_KEY, 0x21d},
+	"KEY_LIGHTS_TOGGLE":            {EV_KEY, 0x21e},
+	"BTN_DPAD_UP":                  {EV_KEY, 0x220},
+	"BTN_DPAD_DOWN":                {EV_KEY, 0x221},
+	"BTN_DPAD_LEFT":                {EV_KEY, 0x222},
+	"BTN_DPAD_RIGHT":               {EV_KEY, 0x223},
+	"KEY_ALS_TOGGLE":               {EV_KEY, 0x230},
+	"KEY_ROTATE_LOCK_TOGGLE":       {EV_KEY, 0x231},
+	"KEY_REFRESH_RATE_TOGGLE":      {EV_KEY, 0x232},
+	"KEY_BUTTONCONFIG":             {EV_KEY, 0x240},
+	"KEY_TASKMANAGER":              {EV_KEY, 0x241},
+	"KEY_JOURNAL":                  {EV_KEY, 0x242},
+	"KEY_CONTROLPANEL":             {EV_KEY, 0x243},
+	"KEY_APPSELECT":                {EV_KEY, 0x244},
+	"KEY_SCREENSAVER":              {EV_KEY, 0x245},
+	"KEY_VOICECOMMAND":             {EV_KEY, 0x246},
+	"KEY_ASSISTANT":                {EV_KEY, 0x247},
+	"KEY_KBD_LAYOUT_NEXT":          {EV_KEY, 0x248},
+	"KEY_EMOJI_PICKER":             {EV_KEY, 0x249},
+	"KEY_DICTATE":                  {EV_KEY, 0x24a},
+	"KEY_BRIGHTNESS_MIN":           {EV_KEY, 0x250},
+	"KEY_KBDINPUTASSIST_PREV":      {EV_KEY, 0x260},
+	"KEY_KBDINPUTASSIST_NEXT":      {EV_KEY, 0x261},
+	"KEY_KBDINPUTASSIST_PREVGROUP": {EV_KEY, 0x262},
+	"KEY_KBDINPUTASSIST_NEXTGROUP": {EV_KEY, 0x263},
+	"KEY_KBDINPUTASSIST_ACCEPT":    {EV_KEY, 0x264},
+	"KEY_KBDINPUTASSIST_CANCEL":    {EV_KEY, 0x265},
+	"KEY_RIGHT_UP":                 {EV_KEY, 0x266},
+	"KEY_RIGHT_DOWN":               {EV_KEY, 0x267},
+	"KEY_LEFT_UP":                  {EV_KEY, 0x268},
+	"KEY_LEFT_DOWN":                {EV_KEY, 0x269},
+	"KEY_ROOT_MENU":                {EV_KEY, 0x26a},
+	"KEY_MEDIA_TOP_MENU":           {EV_KEY, 0x26b},
+	"KEY_NUMERIC_11":               {EV_KEY, 0x26c},
+	"KEY_NUMERIC_12":               {EV_KEY, 0x26d},
+	"KEY_AUDIO_DESC":               {EV_KEY, 0x26e},
+	"KEY_3D_MODE":                  {EV_KEY, 0x26f},
+	"KEY_NEXT_FAVORITE":            {EV_KEY, 0x270},
+	"KEY_STOP_RECORD":              {EV_KEY, 0x271},
+	"KEY_PAUSE_RECORD":             {EV_KEY, 0x272},
+	"KEY_VOD":                      {EV_KEY, 0x273},
+	"KEY_UNMUTE":                   {EV_KEY, 0x274},
+	"KEY_FASTREVERSE":              {EV_KEY, 0x275},
+	"KEY_SLOWREVERSE":              {EV_KEY, 0x276},
+	"KEY_DATA":                     {EV_KEY, 0x277},
+	"KEY_ONSCREEN_KEYBOARD":        {EV_KEY, 0x278},
+	"KEY_PRIVACY_SCREEN_TOGGLE":    {EV_KEY, 0x279},
+	"KEY_SELECTIVE_SCREENSHOT":     {EV_KEY, 0x27a},
+	"KEY_NEXT_ELEMENT":             {EV_KEY, 0x27b},
+	"KEY_PREVIOUS_ELEMENT":         {EV_KEY, 0x27c},
+	"KEY_AUTOPILOT_ENGAGE_TOGGLE":  {EV_KEY, 0x27d},
+	"KEY_MARK_WAYPOINT":            {EV_KEY, 0x27e},
+	"KEY_SOS":                      {EV_KEY, 0x27f},
+	"KEY_NAV_CHART":                {EV_KEY, 0x280},
+	"KEY_FISHING_CHART":            {EV_KEY, 0x281},
+	"KEY_SINGLE_RANGE_RADAR":       {EV_KEY, 0x282},
+	"KEY_DUAL_RANGE_RADAR":         {EV_KEY, 0x283},
+	"KEY_RADAR_OVERLAY":            {EV_KEY, 0x284},
+	"KEY_TRADITIONAL_SONAR":        {EV_KEY, 0x285},
+	"KEY_CLEARVU_SONAR":            {EV_KEY, 0x286},
+	"KEY_SIDEVU_SONAR":             {EV_KEY, 0x287},
+	"KEY_NAV_INFO":                 {EV_KEY, 0x288},
+	"KEY_BRIGHTNESS_MENU":          {EV_KEY, 0x289},
+	"KEY_MACRO1":                   {EV_KEY, 0x290},
+	"KEY_MACRO2":                   {EV_KEY, 0x291},
+	"KEY_MACRO3":                   {EV_KEY, 0x292},
+	"KEY_MACRO4":                   {EV_KEY, 0x293},
+	"KEY_MACRO5":                   {EV_KEY, 0x294},
+	"KEY_MACRO6":                   {EV_KEY, 0x295},
+	"KEY_MACRO7":                   {EV_KEY, 0x296},
+	"KEY_MACRO8":                   {EV_KEY, 0x297},
+	"KEY_MACRO9":                   {EV_KEY, 0x298},
+	"KEY_MACRO10":                  {EV_KEY, 0x299},
+	"KEY_MACRO11":                  {EV_KEY, 0x29a},
+	"KEY_MACRO12":                  {EV_KEY, 0x29b},
+	"KEY_MACRO13":                  {EV_KEY, 0x29c},
+	"KEY_MACRO14":                  {EV_KEY, 0x29d},
+	"KEY_MACRO15":                  {EV_KEY, 0x29e},
+	"KEY_MACRO16":                  {EV_KEY, 0x29f},
+	"KEY_MACRO17":                  {EV_KEY, 0x2a0},
+	"KEY_MACRO18":                  {EV_KEY, 0x2a1},
+	"KEY_MACRO19":                  {EV_KEY, 0x2a2},
+	"KEY_MACRO20":                  {EV_KEY, 0x2a3},
+	"KEY_MACRO21":                  {EV_KEY, 0x2a4},
+	"KEY_MACRO22":                  {EV_KEY, 0x2a5},
+	"KEY_MACRO23":                  {EV_KEY, 0x2a6},
+	"KEY_MACRO24":                  {EV_KEY, 0x2a7},
+	"KEY_MACRO25":                  {EV_KEY, 0x2a8},
+	"KEY_MACRO26":                  {EV_KEY, 0x2a9},
+	"KEY_MACRO27":                  {EV_KEY, 0x2aa},
+	"KEY_MACRO28":                  {EV_KEY, 0x2ab},
+	"KEY_MACRO29":                  {EV_KEY, 0x2ac},
+	"KEY_MACRO30":                  {EV_KEY, 0x2ad},
+	"KEY_MACRO_RECORD_START":       {EV_KEY, 0x2b0},
+	"KEY_MACRO_RECORD_STOP":        {EV_KEY, 0x2b1},
+	"KEY_MACRO_PRESET_CYCLE":       {EV_KEY, 0x2b2},
+	"KEY_MACRO_PRESET1":            {EV_KEY, 0x2b3},
+	"KEY_MACRO_PRESET2":            {EV_KEY, 0x2b4},
+	"KEY_MACRO_PRESET3":            {EV_KEY, 0x2b5},
+	"KEY_KBD_LCD_MENU1":            {EV_KEY, 0x2b8},
+	"KEY_KBD_LCD_MENU2":            {EV_KEY, 0x2b9},
+	"KEY_KBD_LCD_MENU3":            {EV_KEY, 0x2ba},
+	"KEY_KBD_LCD_MENU4":            {EV_KEY, 0x2bb},
+	"KEY_KBD_LCD_MENU5":            {EV_KEY, 0x2bc},
+	"BTN_TRIGGER_HAPPY":            {EV_KEY, 0x2c0},
+	"BTN_TRIGGER_HAPPY1":           {EV_KEY, 0x2c0},
+	"BTN_TRIGGER_HAPPY2":           {EV_KEY, 0x2c1},
+	"BTN_TRIGGER_HAPPY3":           {EV_KEY, 0x2c2},
+	"BTN_TRIGGER_HAPPY4":           {EV_KEY, 0x2c3},
+	"BTN_TRIGGER_HAPPY5":           {EV_KEY, 0x2c4},
+	"BTN_TRIGGER_HAPPY6":           {EV_KEY, 0x2c5},
+	"BTN_TRIGGER_HAPPY7":           {EV_KEY, 0x2c6},
+	"BTN_TRIGGER_HAPPY8":           {EV_KEY, 0x2c7},
+	"BTN_TRIGGER_HAPPY9":           {EV_KEY, 0x2c8},
+	"BTN_TRIGGER_HAPPY10":          {EV_KEY, 0x2c9},
+	"BTN_TRIGGER_HAPPY11":          {EV_KEY, 0x2ca},
+	"BTN_TRIGGER_HAPPY12":          {EV_KEY, 0x2cb},
+	"BTN_TRIGGER_HAPPY13":          {EV_KEY, 0x2cc},
+	"BTN_TRIGGER_HAPPY14":          {EV_KEY, 0x2cd},
+	"BTN_TRIGGER_HAPPY15":          {EV_KEY, 0x2ce},
+	"BTN_TRIGGER_HAPPY16":          {EV_KEY, 0x2cf},
+	"BTN_TRIGGER_HAPPY17":          {EV_KEY, 0x2d0},
+	"BTN_TRIGGER_HAPPY18":          {EV_KEY, 0x2d1},
+	"BTN_TRIGGER_HAPPY19":          {EV_KEY, 0x2d2},
+	"BTN_TRIGGER_HAPPY20":          {EV_KEY, 0x2d3},
+	"BTN_TRIGGER_HAPPY21":          {EV_KEY, 0x2d4},
+	"BTN_TRIGGER_HAPPY22":          {EV_KEY, 0x2d5},
+	"BTN_TRIGGER_HAPPY23":          {EV_KEY, 0x2d6},
+	"BTN_TRIGGER_HAPPY24":          {EV_KEY, 0x2d7},
+	"BTN_TRIGGER_HAPPY25":          {EV_KEY, 0x2d8},
+	"BTN_TRIGGER_HAPPY26":          {EV_KEY, 0x2d9},
+	"BTN_TRIGGER_HAPPY27":          {EV_KEY, 0x2da},
+	"BTN_TRIGGER_HAPPY28":          {EV_KEY, 0x2db},
+	"BTN_TRIGGER_HAPPY29":          {EV_KEY, 0x2dc},
+	"BTN_TRIGGER_HAPPY30":          {EV_KEY, 0x2dd},
+	"BTN_TRIGGER_HAPPY31":          {EV_KEY, 0x2de},
+	"BTN_TRIGGER_HAPPY32":          {EV_KEY, 0x2df},
+	"BTN_TRIGGER_HAPPY33":          {EV_KEY, 0x2e0},
+	"BTN_TRIGGER_HAPPY34":          {EV_KEY, 0x2e1},
+	"BTN_TRIGGER_HAPPY35":          {EV_KEY, 0x2e2},
+	"BTN_TRIGGER_HAPPY36":          {EV_KEY, 0x2e3},
+	"BTN_TRIGGER_HAPPY37":          {EV_KEY, 0x2e4},
+	"BTN_TRIGGER_HAPPY38":          {EV_KEY, 0x2e5},
+	"BTN_TRIGGER_HAPPY39":          {EV_KEY, 0x2e6},
+	"BTN_TRIGGER_HAPPY40":          {EV_KEY, 0x2e7},
+	"REL_X":                        {EV_REL, 0x000},
+	"REL_Y":                        {EV_REL, 0x001},
+	"REL_Z":                        {EV_REL, 0x002},
+	"REL_RX":                       {EV_REL, 0x003},
+	"REL_RY":                       {EV_REL, 0x004},
+	"REL_RZ":                       {EV_REL, 0x005},
+	"REL_HWHEEL":                   {EV_REL, 0x006},
+	"REL_DIAL":                     {EV_REL, 0x007},
+	"REL_WHEEL":                    {EV_REL, 0x008},
+	"REL_MISC":                     {EV_REL, 0x009},
+	"REL_RESERVED":                 {EV_REL, 0x00a},
+	"REL_WHEEL_HI_RES":             {EV_REL, 0x00b},
+	"REL_HWHEEL_HI_RES":            {EV_REL, 0x00c},
+	"ABS_X":                        {EV_ABS, 0x000},
+	"ABS_Y":                        {EV_ABS, 0x001},
+	"ABS_Z":                        {EV_ABS, 0x002},
+	"ABS_RX":                       {EV_ABS, 0x003},
+	"ABS_RY":                       {EV_ABS, 0x004},
+	"ABS_RZ":                       {EV_ABS, 0x005},
+	"ABS_THROTTLE":                 {EV_ABS, 0x006},
+	"ABS_RUDDER":                   {EV_ABS, 0x007},
+	"ABS_WHEEL":                    {EV_ABS, 0x008},
+	"ABS_GAS":                      {EV_ABS, 0x009},
+	"ABS_BRAKE":                    {EV_ABS, 0x00a},
+	"ABS_HAT0X":                    {EV_ABS, 0x010},
+	"ABS_HAT0Y":                    {EV_ABS, 0x011},
+	"ABS_HAT1X":                    {EV_ABS, 0x012},
+	"ABS_HAT1Y":                    {EV_ABS, 0x013},
+	"ABS_HAT2X":                    {EV_ABS, 0x014},
+	"ABS_HAT2Y":                    {EV_ABS, 0x015},
+	"ABS_HAT3X":                    {EV_ABS, 0x016},
+	"ABS_HAT3Y":                    {EV_ABS, 0x017},
+	"ABS_PRESSURE":                 {EV_ABS, 0x018},
+	"ABS_DISTANCE":                 {EV_ABS, 0x019},
+	"ABS_TILT_X":                   {EV_ABS, 0x01a},
+	"ABS_TILT_Y":                   {EV_ABS, 0x01b},
+	"ABS_TOOL_WIDTH":               {EV_ABS, 0x01c},
+	"ABS_VOLUME":                   {EV_ABS, 0x020},
+	"ABS_PROFILE":                  {EV_ABS, 0x021},
+	"ABS_MISC":                     {EV_ABS, 0x028},
+	"ABS_RESERVED":                 {EV_ABS, 0x02e},
+	"ABS_MT_SLOT":                  {EV_ABS, 0x02f},
+	"ABS_MT_TOUCH_MAJOR":           {EV_ABS, 0x030},
+	"ABS_MT_TOUCH_MINOR":           {EV_ABS, 0x031},
+	"ABS_MT_WIDTH_MAJOR":           {EV_ABS, 0x032},
+	"ABS_MT_WIDTH_MINOR":           {EV_ABS, 0x033},
+	"ABS_MT_ORIENTATION":           {EV_ABS, 0x034},
+	"ABS_MT_POSITION_X":            {EV_ABS, 0x035},
+	"ABS_MT_POSITION_Y":            {EV_ABS, 0x036},
+	"ABS_MT_TOOL_TYPE":             {EV_ABS, 0x037},
+	"ABS_MT_BLOB_ID":               {EV_ABS, 0x038},
+	"ABS_MT_TRACKING_ID":           {EV_ABS, 0x039},
+	"ABS_MT_PRESSURE":              {EV_ABS, 0x03a},
+	"ABS_MT_DISTANCE":              {EV_ABS, 0x03b},
+	"ABS_MT_TOOL_X":                {EV_ABS, 0x03c},
+	"ABS_MT_TOOL_Y":                {EV_ABS, 0x03d},
+	"MSC_SERIAL":                   {EV_MSC, 0x000},
+	"MSC_PULSELED":                 {EV_MSC, 0x001},
+	"MSC_GESTURE":                  {EV_MSC, 0x002},
+	"MSC_RAW":                      {EV_MSC, 0x003},
+	"MSC_SCAN":                     {EV_MSC, 0x004},
+	"MSC_TIMESTAMP":                {EV_MSC, 0x005},
+	"SW_LID":                       {EV_SW, 0x000},
+	"SW_TABLET_MODE":               {EV_SW, 0x001},
+	"SW_HEADPHONE_INSERT":          {EV_SW, 0x002},
+	"SW_RFKILL_ALL":                {EV_SW, 0x003},
+	"SW_RADIO":                     {EV_SW, 0x003},
+	"SW_MICROPHONE_INSERT":         {EV_SW, 0x004},
+	"SW_DOCK":                      {EV_SW, 0x005},
+	"SW_LINEOUT_INSERT":            {EV_SW, 0x006},
+	"SW_JACK_PHYSICAL_INSERT":      {EV_SW, 0x007},
+	"SW_VIDEOOUT_INSERT":           {EV_SW, 0x008},
+	"SW_CAMERA_LENS_COVER":         {EV_SW, 0x009},
+	"SW_KEYPAD_SLIDE":              {EV_SW, 0x00a},
+	"SW_FRONT_PROXIMITY":           {EV_SW, 0x00b},
+	"SW_ROTATE_LOCK":               {EV_SW, 0x00c},
+	"SW_LINEIN_INSERT":             {EV_SW, 0x00d},
+	"SW_MUTE_DEVICE":               {EV_SW, 0x00e},
+	"SW_PEN_INSERTED":              {EV_SW, 0x00f},
+	"SW_MACHINE_COVER":             {EV_SW, 0x010},
+	"LED_NUML":                     {EV_LED, 0x000},
+	"LED_CAPSL":                    {EV_LED, 0x001},
+	"LED_SCROLLL":                  {EV_LED, 0x002},
+	"LED_COMPOSE":                  {EV_LED, 0x003},
+	"LED_KANA":                     {EV_LED, 0x004},
+	"LED_SLEEP":                    {EV_LED, 0x005},
+	"LED_SUSPEND":                  {EV_LED, 0x006},
+	"LED_MUTE":                     {EV_LED, 0x007},
+	"LED_MISC":                     {EV_LED, 0x008},
+	"LED_MAIL":                     {EV_LED, 0x009},
+	"LED_CHARGING":                 {EV_LED, 0x00a},
+	"SND_CLICK":                    {EV_SND, 0x000},
+	"SND_BELL":                     {EV_SND, 0x001},
+	"SND_TONE":                     {EV_SND, 0x002},
+	"REP_DELAY":                    {EV_REP, 0x000},
+	"REP_PERIOD":                   {EV_REP, 0x001},
+	"FF_RUMBLE":                    {EV_FF, 0x050},
+	"FF_PERIODIC":                  {EV_FF, 0x051},
+	"FF_CONSTANT":                  {EV_FF, 0x052},
+	"FF_SPRING":                    {EV_FF, 0x053},
+	"FF_FRICTION":                  {EV_FF, 0x054},
+	"FF_DAMPER":                    {EV_FF, 0x055},
+	"FF_INERTIA":                   {EV_FF, 0x056},
+	"FF_RAMP":                      {EV_FF, 0x057},
+	"FF_SQUARE":                    {EV_FF, 0x058},
+	"FF_TRIANGLE":                  {EV_FF, 0x059},
+	"FF_SINE":                      {EV_FF, 0x05a},
+	"FF_SAW_UP":                    {EV_FF, 0x05b},
+	"FF_SAW_DOWN":                  {EV_FF, 0x05c},
+	"FF_CUSTOM":                    {EV_FF, 0x05d},
+	"FF_GAIN":                      {EV_FF, 0x060},
+	"FF_AUTOCENTER":                {EV_FF, 0x061},
+}
@@ -1,19 +1,50 @@
 package evdev
 
-import evdev "github.com/gvalkov/golang-evdev"
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	evdev "github.com/gvalkov/golang-evdev"
+)
 
 //go:generate mockgen -source=${GOFILE} -destination=./${GOPACKAGE}mock/mock_${GOFILE} -package=${GOPACKAGE}mock
 
 type Device interface {
+	// Info returns the identity of the device.
+	Info() DeviceInfo
 	Read() (*InputEvent, error)
+	// Close closes the device, and a blocking Read returns an error.
+	Close() error
+	// Supports reports whether the device can emit the event code.
+	Supports(c Code) bool
+	// AbsInfo returns the current value and the range of the absolute axis code.
+	AbsInfo(code uint16) (AbsInfo, error)
+	// SwitchState reports whether the switch code is on.
+	SwitchState(code uint16) (bool, error)
+}
+
+// AbsInfo is the state of an absolute axis, which corresponds to the input_absinfo struct.
+type AbsInfo struct {
+	Value      int32
+	Minimum    int32
+	Maximum    int32
+	Fuzz       int32
+	Flat       int32
+	Resolution int32
 }
 
 type device struct {
-	d *evdev.InputDevice
+	d    *evdev.InputDevice
+	info DeviceInfo
 }
 
 func NewDevice(d *evdev.InputDevice) Device {
-	return &device{d}
+	return &device{d: d, info: deviceInfo(d)}
+}
+
+func (d *device) Info() DeviceInfo {
+	return d.info
 }
 
 func (d *device) Read() (*InputEvent, error) {
@@ -28,3 +59,44 @@ func (d *device) Read() (*InputEvent, error) {
 		Value: e.Value,
 	}, nil
 }
+
+func (d *device) Close() error {
+	return d.d.File.Close()
+}
+
+func (d *device) Supports(c Code) bool {
+	bits := new([evdev.KEY_MAX/8 + 1]byte)
+	if err := ioctl(d.d.File.Fd(), uintptr(evdev.EVIOCGBIT(int(c.Type), len(bits))), unsafe.Pointer(bits)); err != nil {
+		return false
+	}
+	if int(c.Code/8) >= len(bits) {
+		return false
+	}
+	return bits[c.Code/8]&(1<<(c.Code%8)) != 0
+}
+
+func (d *device) AbsInfo(code uint16) (AbsInfo, error) {
+	var info AbsInfo
+	if err := ioctl(d.d.File.Fd(), uintptr(evdev.EVIOCGABS(int(code))), unsafe.Pointer(&info)); err != nil {
+		return AbsInfo{}, fmt.Errorf("reading absinfo of %s failed: %w", CodeName(EV_ABS, code), err)
+	}
+	return info, nil
+}
+
+func (d *device) SwitchState(code uint16) (bool, error) {
+	bits := new([evdev.MAX_NAME_SIZE]byte)
+	if err := ioctl(d.d.File.Fd(), uintptr(evdev.EVIOCGSW), unsafe.Pointer(bits)); err != nil {
+		return false, fmt.Errorf("reading state of %s failed: %w", CodeName(EV_SW, code), err)
+	}
+	if int(code/8) >= len(bits) {
+		return false, fmt.Errorf("unknown switch %d", code)
+	}
+	return bits[code/8]&(1<<(code%8)) != 0, nil
+}
+
+func ioctl(fd uintptr, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
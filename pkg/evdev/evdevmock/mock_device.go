@@ -34,6 +34,49 @@ func (m *MockDevice) EXPECT() *MockDeviceMockRecorder {
 	return m.recorder
 }
 
+// AbsInfo mocks base method.
+func (m *MockDevice) AbsInfo(code uint16) (evdev.AbsInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AbsInfo", code)
+	ret0, _ := ret[0].(evdev.AbsInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AbsInfo indicates an expected call of AbsInfo.
+func (mr *MockDeviceMockRecorder) AbsInfo(code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AbsInfo", reflect.TypeOf((*MockDevice)(nil).AbsInfo), code)
+}
+
+// Close mocks base method.
+func (m *MockDevice) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockDeviceMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockDevice)(nil).Close))
+}
+
+// Info mocks base method.
+func (m *MockDevice) Info() evdev.DeviceInfo {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Info")
+	ret0, _ := ret[0].(evdev.DeviceInfo)
+	return ret0
+}
+
+// Info indicates an expected call of Info.
+func (mr *MockDeviceMockRecorder) Info() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Info", reflect.TypeOf((*MockDevice)(nil).Info))
+}
+
 // Read mocks base method.
 func (m *MockDevice) Read() (*evdev.InputEvent, error) {
 	m.ctrl.T.Helper()
@@ -48,3 +91,32 @@ func (mr *MockDeviceMockRecorder) Read() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Read", reflect.TypeOf((*MockDevice)(nil).Read))
 }
+
+// Supports mocks base method.
+func (m *MockDevice) Supports(c evdev.Code) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Supports", c)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Supports indicates an expected call of Supports.
+func (mr *MockDeviceMockRecorder) Supports(c interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Supports", reflect.TypeOf((*MockDevice)(nil).Supports), c)
+}
+
+// SwitchState mocks base method.
+func (m *MockDevice) SwitchState(code uint16) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SwitchState", code)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SwitchState indicates an expected call of SwitchState.
+func (mr *MockDeviceMockRecorder) SwitchState(code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SwitchState", reflect.TypeOf((*MockDevice)(nil).SwitchState), code)
+}
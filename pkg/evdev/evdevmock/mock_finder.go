@@ -35,16 +35,16 @@ func (m *MockFinder) EXPECT() *MockFinderMockRecorder {
 }
 
 // Find mocks base method.
-func (m *MockFinder) Find(phys string) (evdev.Device, error) {
+func (m *MockFinder) Find(matcher evdev.Matcher) (evdev.Device, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Find", phys)
+	ret := m.ctrl.Call(m, "Find", matcher)
 	ret0, _ := ret[0].(evdev.Device)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Find indicates an expected call of Find.
-func (mr *MockFinderMockRecorder) Find(phys interface{}) *gomock.Call {
+func (mr *MockFinderMockRecorder) Find(matcher interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockFinder)(nil).Find), phys)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Find", reflect.TypeOf((*MockFinder)(nil).Find), matcher)
 }
@@ -1,11 +1,35 @@
 package evdev
 
-import "syscall"
+import (
+	"fmt"
+	"syscall"
+	"time"
+)
+
+//go:generate go run gen_codes.go /usr/include/linux/input-event-codes.h /usr/include/linux/input.h
+
+const (
+	EV_SYN       = uint16(0x00)
+	EV_KEY       = uint16(0x01)
+	EV_REL       = uint16(0x02)
+	EV_ABS       = uint16(0x03)
+	EV_MSC       = uint16(0x04)
+	EV_SW        = uint16(0x05)
+	EV_LED       = uint16(0x11)
+	EV_SND       = uint16(0x12)
+	EV_REP       = uint16(0x14)
+	EV_FF        = uint16(0x15)
+	EV_PWR       = uint16(0x16)
+	EV_FF_STATUS = uint16(0x17)
+)
 
+// The codes of the multitouch slots of the type B protocol, which gestures are recognized from.
 const (
-	EV_SYN = uint16(0x00)
-	EV_KEY = uint16(0x01)
-	EV_REL = uint16(0x02)
+	SYN_REPORT         = uint16(0x00)
+	ABS_MT_SLOT        = uint16(0x2f)
+	ABS_MT_POSITION_X  = uint16(0x35)
+	ABS_MT_POSITION_Y  = uint16(0x36)
+	ABS_MT_TRACKING_ID = uint16(0x39)
 )
 
 type InputEvent struct {
@@ -14,3 +38,16 @@ type InputEvent struct {
 	Code  uint16          // event code related to the event type
 	Value int32           // event value related to the event type
 }
+
+func (e *InputEvent) String() string {
+	return fmt.Sprintf("%s %s value %d at %d.%06d",
+		TypeName(e.Type), CodeName(e.Type, e.Code), e.Value, e.Time.Sec, e.Time.Usec)
+}
+
+// Timestamp returns the time when the event occurred, or now if the event has no time.
+func (e *InputEvent) Timestamp() time.Time {
+	if e.Time.Sec == 0 && e.Time.Usec == 0 {
+		return time.Now()
+	}
+	return time.Unix(e.Time.Sec, e.Time.Usec*1000)
+}
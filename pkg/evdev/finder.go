@@ -1,18 +1,26 @@
 package evdev
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"strings"
+	"unsafe"
 
 	evdev "github.com/gvalkov/golang-evdev"
 )
 
 //go:generate mockgen -source=${GOFILE} -destination=./${GOPACKAGE}mock/mock_${GOFILE} -package=${GOPACKAGE}mock
 
-var ErrDeviceNotFound = errors.New("device not found")
+var (
+	ErrDeviceNotFound  = errors.New("device not found")
+	ErrAmbiguousDevice = errors.New("device matcher is ambiguous")
+)
 
 type Finder interface {
-	Find(phys string) (Device, error)
+	// Find returns the only device which matches matcher.
+	// It returns ErrAmbiguousDevice if two or more devices match.
+	Find(matcher Matcher) (Device, error)
 }
 
 type finder struct{}
@@ -21,16 +29,63 @@ func NewFinder() Finder {
 	return &finder{}
 }
 
-func (f *finder) Find(phys string) (Device, error) {
+func (f *finder) Find(m Matcher) (Device, error) {
+	cm, err := m.compile()
+	if err != nil {
+		return nil, err
+	}
+
 	devices, err := evdev.ListInputDevices()
 	if err != nil {
 		return nil, fmt.Errorf("listing input devices failed: %w", err)
 	}
 
+	var found []*evdev.InputDevice
+	var infos []string
 	for _, d := range devices {
-		if d.Phys == phys {
-			return NewDevice(d), nil
+		info := deviceInfo(d)
+		if cm.match(info) {
+			found = append(found, d)
+			infos = append(infos, info.String())
+			continue
+		}
+		d.File.Close()
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, ErrDeviceNotFound
+	case 1:
+		return NewDevice(found[0]), nil
+	default:
+		for _, d := range found {
+			d.File.Close()
 		}
+		return nil, fmt.Errorf("%w: %s matches %d devices: %s", ErrAmbiguousDevice, m, len(found), strings.Join(infos, ", "))
+	}
+}
+
+func deviceInfo(d *evdev.InputDevice) DeviceInfo {
+	return DeviceInfo{
+		Path:    d.Fn,
+		Name:    d.Name,
+		Phys:    d.Phys,
+		Uniq:    readUniq(d),
+		Vendor:  d.Vendor,
+		Product: d.Product,
+		Bustype: d.Bustype,
+	}
+}
+
+// readUniq reads the unique identifier of d, which golang-evdev does not provide.
+// It returns an empty string if the device has no identifier.
+func readUniq(d *evdev.InputDevice) string {
+	buf := new([evdev.MAX_NAME_SIZE]byte)
+	if err := ioctl(d.File.Fd(), uintptr(evdev.EVIOCGUNIQ), unsafe.Pointer(buf)); err != nil {
+		return ""
+	}
+	if i := bytes.IndexByte(buf[:], 0); i >= 0 {
+		return string(buf[:i])
 	}
-	return nil, ErrDeviceNotFound
+	return string(buf[:])
 }
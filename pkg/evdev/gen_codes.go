@@ -0,0 +1,158 @@
+//go:build ignore
+// +build ignore
+
+// gen_codes.go generates codes.go from the linux input headers.
+//
+//	go run gen_codes.go /usr/include/linux/input-event-codes.h /usr/include/linux/input.h
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// prefixes maps the prefix of a code name to its event type name.
+var prefixes = map[string]string{
+	"SYN_": "EV_SYN",
+	"KEY_": "EV_KEY",
+	"BTN_": "EV_KEY",
+	"REL_": "EV_REL",
+	"ABS_": "EV_ABS",
+	"MSC_": "EV_MSC",
+	"SW_":  "EV_SW",
+	"LED_": "EV_LED",
+	"SND_": "EV_SND",
+	"REP_": "EV_REP",
+	"FF_":  "EV_FF",
+}
+
+// skips are names which are not event codes even though they have a known prefix.
+var skips = regexp.MustCompile(`(_MAX|_CNT|_MIN_INTERESTING|_EFFECT_MIN|_EFFECT_MAX|_WAVEFORM_MIN|_WAVEFORM_MAX|_MAX_EFFECTS)$|^FF_STATUS_|^EV_VERSION$`)
+
+var define = regexp.MustCompile(`^#define\s+([A-Z][A-Z0-9_]*)\s+(\S+)`)
+
+type code struct {
+	name  string
+	typ   string
+	value uint64
+	// alias is true if the code is defined by another name, such as KEY_HANGUEL.
+	alias bool
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		log.Fatal("usage: go run gen_codes.go HEADER...")
+	}
+
+	types := map[string]uint64{}
+	values := map[string]uint64{}
+	var codes []code
+	var ok bool
+	for _, name := range os.Args[1:] {
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			m := define.FindStringSubmatch(s.Text())
+			if m == nil || skips.MatchString(m[1]) {
+				continue
+			}
+			v, err := strconv.ParseUint(m[2], 0, 16)
+			alias := err != nil
+			if alias {
+				if v, ok = values[m[2]]; !ok {
+					continue
+				}
+			}
+			if strings.HasPrefix(m[1], "EV_") {
+				types[m[1]] = v
+				continue
+			}
+			for p, typ := range prefixes {
+				if strings.HasPrefix(m[1], p) {
+					values[m[1]] = v
+					codes = append(codes, code{name: m[1], typ: typ, value: v, alias: alias})
+				}
+			}
+		}
+		if err := s.Err(); err != nil {
+			log.Fatal(err)
+		}
+		f.Close()
+	}
+
+	// later definitions take precedence for names of codes,
+	// since group names such as BTN_MISC precede the name of the first code.
+	names := map[string]map[uint64]string{}
+	for _, c := range codes {
+		if names[c.typ] == nil {
+			names[c.typ] = map[uint64]string{}
+		}
+		if c.alias {
+			continue
+		}
+		names[c.typ][c.value] = c.name
+	}
+
+	var typeNames []string
+	for t := range types {
+		typeNames = append(typeNames, t)
+	}
+	sort.Slice(typeNames, func(i, j int) bool { return types[typeNames[i]] < types[typeNames[j]] })
+	sort.SliceStable(codes, func(i, j int) bool {
+		if codes[i].typ != codes[j].typ {
+			return types[codes[i].typ] < types[codes[j].typ]
+		}
+		return codes[i].value < codes[j].value
+	})
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by gen_codes.go; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package evdev\n\n")
+
+	fmt.Fprintf(&b, "var typeNames = map[uint16]string{\n")
+	for _, t := range typeNames {
+		fmt.Fprintf(&b, "0x%02x: %q,\n", types[t], t)
+	}
+	fmt.Fprintf(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "var codeNames = map[uint16]map[uint16]string{\n")
+	for _, t := range typeNames {
+		if len(names[t]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%s: {\n", t)
+		for _, c := range codes {
+			if c.typ == t && names[t][c.value] == c.name {
+				fmt.Fprintf(&b, "0x%03x: %q,\n", c.value, c.name)
+			}
+		}
+		fmt.Fprintf(&b, "},\n")
+	}
+	fmt.Fprintf(&b, "}\n\n")
+
+	fmt.Fprintf(&b, "var namedCodes = map[string]Code{\n")
+	for _, c := range codes {
+		fmt.Fprintf(&b, "%q: {%s, 0x%03x},\n", c.name, c.typ, c.value)
+	}
+	fmt.Fprintf(&b, "}\n")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("codes.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
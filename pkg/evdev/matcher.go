@@ -0,0 +1,141 @@
+package evdev
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Matcher describes the device to watch.
+// Every non-zero field must match, zero fields match any device.
+type Matcher struct {
+	Phys      string `yaml:"phys"`
+	Name      string `yaml:"name"`
+	NameRegex string `yaml:"name_regex"`
+	Uniq      string `yaml:"uniq"`
+	Vendor    uint16 `yaml:"vendor"`
+	Product   uint16 `yaml:"product"`
+	Bustype   uint16 `yaml:"bustype"`
+	// Path is a device node or a symlink to it,
+	// such as /dev/input/by-id/... or /dev/input/by-path/...
+	Path string `yaml:"path"`
+}
+
+// DeviceInfo is the identity of a connected device which Matcher is compared with.
+type DeviceInfo struct {
+	Path    string
+	Name    string
+	Phys    string
+	Uniq    string
+	Vendor  uint16
+	Product uint16
+	Bustype uint16
+}
+
+func (i DeviceInfo) String() string {
+	return fmt.Sprintf("%s (name %q, phys %q, uniq %q, bus 0x%04x, vendor 0x%04x, product 0x%04x)",
+		i.Path, i.Name, i.Phys, i.Uniq, i.Bustype, i.Vendor, i.Product)
+}
+
+// IsZero reports whether m has no conditions.
+func (m Matcher) IsZero() bool {
+	return m == Matcher{}
+}
+
+// Validate reports whether m can be used to find a device.
+func (m Matcher) Validate() error {
+	if m.IsZero() {
+		return errors.New("device matcher has no conditions")
+	}
+	if m.Name != "" && m.NameRegex != "" {
+		return errors.New("device matcher cannot have both name and name_regex")
+	}
+	if m.NameRegex != "" {
+		if _, err := regexp.Compile(m.NameRegex); err != nil {
+			return fmt.Errorf("invalid name_regex: %w", err)
+		}
+	}
+	return nil
+}
+
+func (m Matcher) String() string {
+	var s []string
+	if m.Path != "" {
+		s = append(s, fmt.Sprintf("path=%s", m.Path))
+	}
+	if m.Phys != "" {
+		s = append(s, fmt.Sprintf("phys=%s", m.Phys))
+	}
+	if m.Name != "" {
+		s = append(s, fmt.Sprintf("name=%q", m.Name))
+	}
+	if m.NameRegex != "" {
+		s = append(s, fmt.Sprintf("name_regex=%q", m.NameRegex))
+	}
+	if m.Uniq != "" {
+		s = append(s, fmt.Sprintf("uniq=%s", m.Uniq))
+	}
+	if m.Vendor != 0 {
+		s = append(s, fmt.Sprintf("vendor=0x%04x", m.Vendor))
+	}
+	if m.Product != 0 {
+		s = append(s, fmt.Sprintf("product=0x%04x", m.Product))
+	}
+	if m.Bustype != 0 {
+		s = append(s, fmt.Sprintf("bustype=0x%04x", m.Bustype))
+	}
+	return strings.Join(s, ",")
+}
+
+// compiledMatcher is Matcher prepared for comparing with many devices.
+type compiledMatcher struct {
+	m        Matcher
+	nameRe   *regexp.Regexp
+	realPath string
+}
+
+// compile resolves the path symlink and the name regexp of m.
+// It returns ErrDeviceNotFound if the path does not exist.
+func (m Matcher) compile() (*compiledMatcher, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	c := &compiledMatcher{m: m}
+	if m.NameRegex != "" {
+		c.nameRe = regexp.MustCompile(m.NameRegex)
+	}
+	if m.Path != "" {
+		p, err := filepath.EvalSymlinks(m.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: resolving path %s failed: %s", ErrDeviceNotFound, m.Path, err)
+		}
+		c.realPath = p
+	}
+	return c, nil
+}
+
+func (c *compiledMatcher) match(i DeviceInfo) bool {
+	m := c.m
+	switch {
+	case c.realPath != "" && c.realPath != i.Path:
+		return false
+	case m.Phys != "" && m.Phys != i.Phys:
+		return false
+	case m.Name != "" && m.Name != i.Name:
+		return false
+	case c.nameRe != nil && !c.nameRe.MatchString(i.Name):
+		return false
+	case m.Uniq != "" && m.Uniq != i.Uniq:
+		return false
+	case m.Vendor != 0 && m.Vendor != i.Vendor:
+		return false
+	case m.Product != 0 && m.Product != i.Product:
+		return false
+	case m.Bustype != 0 && m.Bustype != i.Bustype:
+		return false
+	}
+	return true
+}
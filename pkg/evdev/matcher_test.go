@@ -0,0 +1,46 @@
+package evdev
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatcher_match(t *testing.T) {
+	info := DeviceInfo{
+		Path:    "/dev/input/event3",
+		Name:    "Logitech USB Receiver",
+		Phys:    "usb-0000:00:14.0-1/input0",
+		Uniq:    "",
+		Vendor:  0x046d,
+		Product: 0xc52b,
+		Bustype: 0x03,
+	}
+
+	tests := []struct {
+		name string
+		m    Matcher
+		want bool
+	}{
+		{"phys", Matcher{Phys: "usb-0000:00:14.0-1/input0"}, true},
+		{"phys mismatch", Matcher{Phys: "usb-0000:00:14.0-2/input0"}, false},
+		{"name", Matcher{Name: "Logitech USB Receiver"}, true},
+		{"name regex", Matcher{NameRegex: "^Logitech"}, true},
+		{"name regex mismatch", Matcher{NameRegex: "Keyboard$"}, false},
+		{"ids", Matcher{Vendor: 0x046d, Product: 0xc52b, Bustype: 0x03}, true},
+		{"product mismatch", Matcher{Vendor: 0x046d, Product: 0xc52c}, false},
+		{"uniq mismatch", Matcher{Name: "Logitech USB Receiver", Uniq: "aa:bb"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm, err := tt.m.compile()
+			require.NoError(t, err)
+			require.Equal(t, tt.want, cm.match(info))
+		})
+	}
+}
+
+func TestMatcher_compile_PathNotFound(t *testing.T) {
+	_, err := Matcher{Path: "/nonexistent/by-id/usb-foo-event-kbd"}.compile()
+	require.ErrorIs(t, err, ErrDeviceNotFound)
+}
@@ -0,0 +1,219 @@
+package gesture
+
+import (
+	"math"
+	"time"
+
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+)
+
+// Recognizer recognizes strokes from the ABS_MT_* events of multitouch devices, which use the type B protocol.
+type Recognizer interface {
+	// Feed processes ev, and returns the stroke when its last finger is lifted.
+	Feed(ev *evdev.InputEvent) (Stroke, bool)
+	// SetRange sets the ranges of ABS_MT_POSITION_X and ABS_MT_POSITION_Y,
+	// which normalize positions into 0 to 1. Without them, positions are in the units of the device.
+	SetRange(x, y evdev.AbsInfo)
+	// Reset forgets the fingers on the device.
+	Reset()
+}
+
+// Stroke is a touch from the first finger down to the last finger up.
+type Stroke struct {
+	// Fingers is the largest number of fingers touched at once.
+	Fingers  int
+	Duration time.Duration
+	// DX and DY are the average movement of the fingers, where DY is positive downwards.
+	DX, DY float64
+	// Spread is the change of the average distance of the fingers from their center while Fingers touched,
+	// it is positive if they spread out.
+	Spread float64
+	// Travel is the longest distance of a finger from where it touched.
+	Travel float64
+}
+
+type point struct {
+	x, y float64
+}
+
+func (p point) distance(q point) float64 {
+	return math.Hypot(p.x-q.x, p.y-q.y)
+}
+
+type contact struct {
+	// started is true once the position of the contact is known.
+	started    bool
+	start, pos point
+}
+
+type slot struct {
+	x, y    int32
+	contact *contact
+}
+
+func NewRecognizer() Recognizer {
+	return &recognizer{
+		slots: make(map[int32]*slot),
+	}
+}
+
+type recognizer struct {
+	slots map[int32]*slot
+	cur   int32
+	// contacts is the contacts of the stroke, including lifted ones.
+	contacts    []*contact
+	start       time.Time
+	fingers     int
+	spreadStart float64
+	spreadEnd   float64
+	xRange      *evdev.AbsInfo
+	yRange      *evdev.AbsInfo
+}
+
+func (r *recognizer) Feed(ev *evdev.InputEvent) (Stroke, bool) {
+	switch ev.Type {
+	case evdev.EV_ABS:
+		switch ev.Code {
+		case evdev.ABS_MT_SLOT:
+			r.cur = ev.Value
+		case evdev.ABS_MT_TRACKING_ID:
+			s := r.slot(r.cur)
+			if ev.Value < 0 {
+				s.contact = nil
+				break
+			}
+			s.contact = &contact{}
+			r.contacts = append(r.contacts, s.contact)
+		case evdev.ABS_MT_POSITION_X:
+			r.slot(r.cur).x = ev.Value
+		case evdev.ABS_MT_POSITION_Y:
+			r.slot(r.cur).y = ev.Value
+		}
+	case evdev.EV_SYN:
+		if ev.Code == evdev.SYN_REPORT {
+			return r.frame(ev.Timestamp())
+		}
+	}
+	return Stroke{}, false
+}
+
+func (r *recognizer) slot(n int32) *slot {
+	s, ok := r.slots[n]
+	if !ok {
+		s = &slot{}
+		r.slots[n] = s
+	}
+	return s
+}
+
+// frame applies the events since the last frame at t.
+func (r *recognizer) frame(t time.Time) (Stroke, bool) {
+	if len(r.contacts) == 0 {
+		return Stroke{}, false
+	}
+
+	var active []*contact
+	for _, s := range r.slots {
+		if s.contact == nil {
+			continue
+		}
+		p := r.normalize(s.x, s.y)
+		if !s.contact.started {
+			s.contact.started = true
+			s.contact.start = p
+		}
+		s.contact.pos = p
+		active = append(active, s.contact)
+	}
+	if r.start.IsZero() {
+		r.start = t
+	}
+	switch {
+	case len(active) > r.fingers:
+		r.fingers = len(active)
+		r.spreadStart = spread(active)
+		r.spreadEnd = r.spreadStart
+	case len(active) == r.fingers:
+		r.spreadEnd = spread(active)
+	}
+	if len(active) > 0 {
+		return Stroke{}, false
+	}
+
+	s := r.stroke(t)
+	r.resetStroke()
+	return s, s.Fingers > 0
+}
+
+func (r *recognizer) stroke(end time.Time) Stroke {
+	s := Stroke{
+		Fingers:  r.fingers,
+		Duration: end.Sub(r.start),
+		Spread:   r.spreadEnd - r.spreadStart,
+	}
+	n := 0
+	for _, c := range r.contacts {
+		if !c.started {
+			continue
+		}
+		n++
+		s.DX += c.pos.x - c.start.x
+		s.DY += c.pos.y - c.start.y
+		s.Travel = math.Max(s.Travel, c.pos.distance(c.start))
+	}
+	if n > 0 {
+		s.DX /= float64(n)
+		s.DY /= float64(n)
+	}
+	return s
+}
+
+// spread returns the average distance of contacts from their center.
+func spread(contacts []*contact) float64 {
+	if len(contacts) < 2 {
+		return 0
+	}
+	var center point
+	for _, c := range contacts {
+		center.x += c.pos.x
+		center.y += c.pos.y
+	}
+	center.x /= float64(len(contacts))
+	center.y /= float64(len(contacts))
+
+	var sum float64
+	for _, c := range contacts {
+		sum += c.pos.distance(center)
+	}
+	return sum / float64(len(contacts))
+}
+
+func (r *recognizer) normalize(x, y int32) point {
+	return point{x: scale(x, r.xRange), y: scale(y, r.yRange)}
+}
+
+func scale(v int32, info *evdev.AbsInfo) float64 {
+	if info == nil || info.Maximum <= info.Minimum {
+		return float64(v)
+	}
+	return float64(v-info.Minimum) / float64(info.Maximum-info.Minimum)
+}
+
+func (r *recognizer) SetRange(x, y evdev.AbsInfo) {
+	r.xRange = &x
+	r.yRange = &y
+}
+
+func (r *recognizer) Reset() {
+	r.slots = make(map[int32]*slot)
+	r.cur = 0
+	r.resetStroke()
+}
+
+func (r *recognizer) resetStroke() {
+	r.contacts = nil
+	r.start = time.Time{}
+	r.fingers = 0
+	r.spreadStart = 0
+	r.spreadEnd = 0
+}
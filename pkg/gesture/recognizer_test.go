@@ -0,0 +1,90 @@
+package gesture_test
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"github.com/hareku/evdev-trigger/pkg/gesture"
+	"github.com/stretchr/testify/require"
+)
+
+// touch is a finger in a slot, where id -1 lifts the finger.
+type touch struct {
+	slot, id, x, y int32
+}
+
+// feedFrame feeds the touches and SYN_REPORT at offset, and returns the result of SYN_REPORT.
+func feedFrame(r gesture.Recognizer, offset time.Duration, touches ...touch) (gesture.Stroke, bool) {
+	base := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tv := syscall.NsecToTimeval(base.Add(offset).UnixNano())
+	abs := func(code uint16, v int32) {
+		_, ok := r.Feed(&evdev.InputEvent{Time: tv, Type: evdev.EV_ABS, Code: code, Value: v})
+		if ok {
+			panic("stroke before SYN_REPORT")
+		}
+	}
+	for _, t := range touches {
+		abs(evdev.ABS_MT_SLOT, t.slot)
+		if t.id != 0 {
+			abs(evdev.ABS_MT_TRACKING_ID, t.id)
+		}
+		if t.id >= 0 {
+			abs(evdev.ABS_MT_POSITION_X, t.x)
+			abs(evdev.ABS_MT_POSITION_Y, t.y)
+		}
+	}
+	return r.Feed(&evdev.InputEvent{Time: tv, Type: evdev.EV_SYN, Code: evdev.SYN_REPORT})
+}
+
+func TestRecognizer_Swipe(t *testing.T) {
+	r := gesture.NewRecognizer()
+	r.SetRange(evdev.AbsInfo{Maximum: 1000}, evdev.AbsInfo{Maximum: 500})
+
+	_, ok := feedFrame(r, 0, touch{0, 1, 600, 250}, touch{1, 2, 700, 250})
+	require.False(t, ok)
+	_, ok = feedFrame(r, 50*time.Millisecond, touch{0, 0, 400, 260}, touch{1, 0, 500, 260})
+	require.False(t, ok)
+	_, ok = feedFrame(r, 100*time.Millisecond, touch{slot: 0, id: -1})
+	require.False(t, ok)
+
+	s, ok := feedFrame(r, 120*time.Millisecond, touch{slot: 1, id: -1})
+	require.True(t, ok)
+	require.Equal(t, 2, s.Fingers)
+	require.Equal(t, 120*time.Millisecond, s.Duration)
+	require.InDelta(t, -0.2, s.DX, 1e-9)
+	require.InDelta(t, 0.02, s.DY, 1e-9)
+	require.InDelta(t, 0, s.Spread, 1e-9)
+}
+
+func TestRecognizer_Pinch(t *testing.T) {
+	r := gesture.NewRecognizer()
+	r.SetRange(evdev.AbsInfo{Maximum: 100}, evdev.AbsInfo{Maximum: 100})
+
+	feedFrame(r, 0, touch{0, 1, 40, 50})
+	feedFrame(r, 10*time.Millisecond, touch{1, 2, 60, 50})
+	feedFrame(r, 50*time.Millisecond, touch{0, 0, 20, 50}, touch{1, 0, 80, 50})
+	s, ok := feedFrame(r, 60*time.Millisecond, touch{slot: 0, id: -1}, touch{slot: 1, id: -1})
+	require.True(t, ok)
+	require.Equal(t, 2, s.Fingers)
+	// the distance from the center grows from 0.1 to 0.3.
+	require.InDelta(t, 0.2, s.Spread, 1e-9)
+	require.InDelta(t, 0.2, s.Travel, 1e-9)
+}
+
+func TestRecognizer_Reset(t *testing.T) {
+	r := gesture.NewRecognizer()
+
+	feedFrame(r, 0, touch{0, 1, 40, 50})
+	r.Reset()
+
+	// a finger which touched before Reset is forgotten.
+	_, ok := feedFrame(r, 10*time.Millisecond, touch{slot: 0, id: -1})
+	require.False(t, ok)
+
+	feedFrame(r, 20*time.Millisecond, touch{0, 2, 40, 50})
+	s, ok := feedFrame(r, 30*time.Millisecond, touch{slot: 0, id: -1})
+	require.True(t, ok)
+	require.Equal(t, gesture.Stroke{Fingers: 1, Duration: 10 * time.Millisecond}, s)
+}
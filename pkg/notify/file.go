@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// NewFileNotifier returns a notifier which broadcasts when the file is written or replaced.
+func NewFileNotifier(name string) Notifier {
+	return &fileNotifier{
+		name: filepath.Clean(name),
+	}
+}
+
+type fileNotifier struct {
+	name string
+}
+
+func (n *fileNotifier) Subscribe(ctx context.Context, cnd *sync.Cond) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// The directory is watched since editors often replace the file by renaming.
+	dir := filepath.Dir(n.name)
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("failed to watch dir %s: %w", dir, err)
+	}
+
+	const ops = fsnotify.Create | fsnotify.Write | fsnotify.Rename
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if filepath.Clean(event.Name) == n.name && event.Op&ops != 0 {
+				cnd.Broadcast()
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return fmt.Errorf("error in watcher: %w", err)
+		}
+	}
+}
@@ -3,16 +3,36 @@ package notify
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 
 	"github.com/fsnotify/fsnotify"
 )
 
-func NewFsNotifier() Notifier {
-	return &fsNotifier{}
+type NewFsNotifierInput struct {
+	// Dir is the directory of the device nodes, "/dev/input" if empty.
+	Dir string
 }
 
-type fsNotifier struct{}
+// NewFsNotifier returns a notifier which broadcasts when a device is connected or disconnected.
+func NewFsNotifier(in NewFsNotifierInput) Notifier {
+	dir := in.Dir
+	if dir == "" {
+		dir = "/dev/input"
+	}
+	dir = filepath.Clean(dir)
+	return &fsNotifier{
+		dir:         dir,
+		symlinkDirs: []string{filepath.Join(dir, "by-id"), filepath.Join(dir, "by-path")},
+	}
+}
+
+type fsNotifier struct {
+	dir string
+	// symlinkDirs is the directories of the symlinks to the devices, which udev creates.
+	symlinkDirs []string
+}
 
 func (n *fsNotifier) Subscribe(ctx context.Context, cnd *sync.Cond) error {
 	watcher, err := fsnotify.NewWatcher()
@@ -21,8 +41,20 @@ func (n *fsNotifier) Subscribe(ctx context.Context, cnd *sync.Cond) error {
 	}
 	defer watcher.Close()
 
-	if err := watcher.Add("/dev/input/"); err != nil {
-		return fmt.Errorf("failed to watch dir /dev/input/: %w", err)
+	if err := watcher.Add(n.dir); err != nil {
+		return fmt.Errorf("failed to watch dir %s: %w", n.dir, err)
+	}
+	// Symlinks in by-id and by-path are created by udev after the device node,
+	// so they are watched too for matching devices by path.
+	// A missing directory is watched when it is created, and it is checked beforehand
+	// since fsnotify does not wrap the error of resolving it.
+	for _, dir := range n.symlinkDirs {
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			return fmt.Errorf("failed to watch dir %s: %w", dir, err)
+		}
 	}
 
 	for {
@@ -33,7 +65,13 @@ func (n *fsNotifier) Subscribe(ctx context.Context, cnd *sync.Cond) error {
 			if !ok {
 				return nil
 			}
-			if event.Op&fsnotify.Create == fsnotify.Create {
+			if event.Op&fsnotify.Create == fsnotify.Create && n.isSymlinkDir(event.Name) {
+				if err := watcher.Add(event.Name); err != nil {
+					return fmt.Errorf("failed to watch dir %s: %w", event.Name, err)
+				}
+			}
+			// a removal resolves a matcher which is ambiguous with the removed device.
+			if event.Op&(fsnotify.Create|fsnotify.Remove) != 0 {
 				cnd.Broadcast()
 			}
 		case err, ok := <-watcher.Errors:
@@ -44,3 +82,12 @@ func (n *fsNotifier) Subscribe(ctx context.Context, cnd *sync.Cond) error {
 		}
 	}
 }
+
+func (n *fsNotifier) isSymlinkDir(name string) bool {
+	for _, dir := range n.symlinkDirs {
+		if filepath.Clean(name) == dir {
+			return true
+		}
+	}
+	return false
+}
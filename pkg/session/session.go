@@ -0,0 +1,198 @@
+package session
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+//go:generate mockgen -source=${GOFILE} -destination=./${GOPACKAGE}mock/mock_${GOFILE} -package=${GOPACKAGE}mock
+
+// Vars is the names of the environment variables taken from the session.
+var Vars = []string{
+	"DISPLAY",
+	"WAYLAND_DISPLAY",
+	"XAUTHORITY",
+	"XDG_RUNTIME_DIR",
+	"DBUS_SESSION_BUS_ADDRESS",
+	"XDG_SESSION_ID",
+	"XDG_SESSION_TYPE",
+	"XDG_CURRENT_DESKTOP",
+}
+
+// ErrNoActiveSession is returned when the seat has no active session, such as on the login screen.
+var ErrNoActiveSession = errors.New("no active session")
+
+// Session is a login session of a user.
+type Session struct {
+	ID string
+	// UID is the numeric id of the user.
+	UID  string
+	User string
+	// Env is the environment variables of the session in Vars, in the form "key=value".
+	Env []string
+}
+
+type Finder interface {
+	// ActiveUser returns the active session of seat0.
+	ActiveUser() (Session, error)
+}
+
+type NewFinderInput struct {
+	// RunDir is the directory of the runtime files of logind and users, "/run" if empty.
+	RunDir string
+	// ProcDir is the directory of procfs, "/proc" if empty.
+	ProcDir string
+}
+
+// NewFinder returns a finder which reads the runtime files of systemd-logind,
+// and the environment of the processes of the session from procfs.
+func NewFinder(in NewFinderInput) Finder {
+	f := &finder{run: in.RunDir, proc: in.ProcDir}
+	if f.run == "" {
+		f.run = "/run"
+	}
+	if f.proc == "" {
+		f.proc = "/proc"
+	}
+	return f
+}
+
+type finder struct {
+	run  string
+	proc string
+}
+
+func (f *finder) ActiveUser() (Session, error) {
+	seat, err := readEnvFile(filepath.Join(f.run, "systemd/seats/seat0"))
+	if err != nil {
+		return Session{}, fmt.Errorf("reading seat failed: %w", err)
+	}
+	id := seat["ACTIVE"]
+	if id == "" {
+		return Session{}, ErrNoActiveSession
+	}
+	info, err := readEnvFile(filepath.Join(f.run, "systemd/sessions", id))
+	if err != nil {
+		return Session{}, fmt.Errorf("reading session %s failed: %w", id, err)
+	}
+	s := Session{ID: id, UID: info["UID"], User: info["USER"]}
+	if s.UID == "" {
+		return Session{}, fmt.Errorf("session %s has no user", id)
+	}
+
+	env := f.processEnv(id, s.UID)
+	// logind knows the display of X11 sessions, and the runtime directory of the user has the session bus.
+	runtime := filepath.Join(f.run, "user", s.UID)
+	if env["DISPLAY"] == "" && info["DISPLAY"] != "" {
+		env["DISPLAY"] = info["DISPLAY"]
+	}
+	if env["XDG_RUNTIME_DIR"] == "" && isDir(runtime) {
+		env["XDG_RUNTIME_DIR"] = runtime
+	}
+	if env["DBUS_SESSION_BUS_ADDRESS"] == "" {
+		if _, err := os.Stat(filepath.Join(runtime, "bus")); err == nil {
+			env["DBUS_SESSION_BUS_ADDRESS"] = "unix:path=" + filepath.Join(runtime, "bus")
+		}
+	}
+	if env["XDG_SESSION_ID"] == "" {
+		env["XDG_SESSION_ID"] = id
+	}
+	if env["XDG_SESSION_TYPE"] == "" && info["TYPE"] != "" {
+		env["XDG_SESSION_TYPE"] = info["TYPE"]
+	}
+	for _, k := range Vars {
+		if v := env[k]; v != "" {
+			s.Env = append(s.Env, k+"="+v)
+		}
+	}
+	return s, nil
+}
+
+// processEnv returns the variables in Vars of the process of the session which has the most of them.
+// The graphical processes of the session have the display, while its leader often does not.
+func (f *finder) processEnv(id, uid string) map[string]string {
+	best := make(map[string]string)
+	entries, err := os.ReadDir(f.proc)
+	if err != nil {
+		return best
+	}
+	for _, e := range entries {
+		if _, err := strconv.Atoi(e.Name()); err != nil {
+			continue
+		}
+		dir := filepath.Join(f.proc, e.Name())
+		if !ownedBy(dir, uid) {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(dir, "environ"))
+		if err != nil {
+			continue
+		}
+		env := make(map[string]string)
+		for _, kv := range bytes.Split(b, []byte{0}) {
+			if kv := strings.SplitN(string(kv), "=", 2); len(kv) == 2 {
+				env[kv[0]] = kv[1]
+			}
+		}
+		if env["XDG_SESSION_ID"] != id {
+			continue
+		}
+		if n := countVars(env); n > countVars(best) {
+			best = env
+		}
+	}
+	return best
+}
+
+func countVars(env map[string]string) int {
+	n := 0
+	for _, k := range Vars {
+		if env[k] != "" {
+			n++
+		}
+	}
+	return n
+}
+
+func ownedBy(name, uid string) bool {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return false
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	return ok && strconv.FormatUint(uint64(st.Uid), 10) == uid
+}
+
+func isDir(name string) bool {
+	fi, err := os.Stat(name)
+	return err == nil && fi.IsDir()
+}
+
+// readEnvFile reads a file of "key=value" lines, such as the runtime files of logind.
+func readEnvFile(name string) (map[string]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	vars := make(map[string]string)
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if kv := strings.SplitN(line, "=", 2); len(kv) == 2 {
+			vars[kv[0]] = kv[1]
+		}
+	}
+	return vars, sc.Err()
+}
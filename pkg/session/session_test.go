@@ -0,0 +1,58 @@
+package session_test
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hareku/evdev-trigger/pkg/session"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(name), 0o755))
+	require.NoError(t, os.WriteFile(name, []byte(content), 0o644))
+}
+
+func TestFinder_ActiveUser(t *testing.T) {
+	run, proc := t.TempDir(), t.TempDir()
+	uid := strconv.Itoa(os.Getuid())
+	writeFile(t, filepath.Join(run, "systemd/seats/seat0"), "# This is private data.\nIS_SEAT0=1\nACTIVE=3\nACTIVE_UID="+uid+"\n")
+	writeFile(t, filepath.Join(run, "systemd/sessions/3"), "UID="+uid+"\nUSER=alice\nACTIVE=1\nTYPE=wayland\nLEADER=100\n")
+	require.NoError(t, os.MkdirAll(filepath.Join(run, "user", uid), 0o700))
+	writeFile(t, filepath.Join(run, "user", uid, "bus"), "")
+
+	environ := func(vars ...string) string {
+		return strings.Join(vars, "\x00") + "\x00"
+	}
+	// the leader of the session does not have the display, and the process of another session is skipped.
+	writeFile(t, filepath.Join(proc, "100/environ"), environ("XDG_SESSION_ID=3", "HOME=/home/alice"))
+	writeFile(t, filepath.Join(proc, "200/environ"), environ("XDG_SESSION_ID=3", "WAYLAND_DISPLAY=wayland-0", "XDG_CURRENT_DESKTOP=GNOME"))
+	writeFile(t, filepath.Join(proc, "300/environ"), environ("XDG_SESSION_ID=4", "WAYLAND_DISPLAY=wayland-1", "DISPLAY=:1", "XDG_CURRENT_DESKTOP=KDE"))
+	writeFile(t, filepath.Join(proc, "self/environ"), environ("XDG_SESSION_ID=3", "WAYLAND_DISPLAY=wayland-2", "DISPLAY=:2", "XDG_CURRENT_DESKTOP=KDE"))
+
+	f := session.NewFinder(session.NewFinderInput{RunDir: run, ProcDir: proc})
+	s, err := f.ActiveUser()
+	require.NoError(t, err)
+	require.Equal(t, session.Session{
+		ID:   "3",
+		UID:  uid,
+		User: "alice",
+		Env: []string{
+			"WAYLAND_DISPLAY=wayland-0",
+			"XDG_RUNTIME_DIR=" + filepath.Join(run, "user", uid),
+			"DBUS_SESSION_BUS_ADDRESS=unix:path=" + filepath.Join(run, "user", uid, "bus"),
+			"XDG_SESSION_ID=3",
+			"XDG_SESSION_TYPE=wayland",
+			"XDG_CURRENT_DESKTOP=GNOME",
+		},
+	}, s)
+
+	// on the login screen, the seat has no active session.
+	writeFile(t, filepath.Join(run, "systemd/seats/seat0"), "IS_SEAT0=1\n")
+	_, err = f.ActiveUser()
+	require.ErrorIs(t, err, session.ErrNoActiveSession)
+}
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: session.go
+
+// Package sessionmock is a generated GoMock package.
+package sessionmock
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	session "github.com/hareku/evdev-trigger/pkg/session"
+)
+
+// MockFinder is a mock of Finder interface.
+type MockFinder struct {
+	ctrl     *gomock.Controller
+	recorder *MockFinderMockRecorder
+}
+
+// MockFinderMockRecorder is the mock recorder for MockFinder.
+type MockFinderMockRecorder struct {
+	mock *MockFinder
+}
+
+// NewMockFinder creates a new mock instance.
+func NewMockFinder(ctrl *gomock.Controller) *MockFinder {
+	mock := &MockFinder{ctrl: ctrl}
+	mock.recorder = &MockFinderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockFinder) EXPECT() *MockFinderMockRecorder {
+	return m.recorder
+}
+
+// ActiveUser mocks base method.
+func (m *MockFinder) ActiveUser() (session.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ActiveUser")
+	ret0, _ := ret[0].(session.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ActiveUser indicates an expected call of ActiveUser.
+func (mr *MockFinderMockRecorder) ActiveUser() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ActiveUser", reflect.TypeOf((*MockFinder)(nil).ActiveUser))
+}
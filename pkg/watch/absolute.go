@@ -0,0 +1,96 @@
+package watch
+
+import (
+	"github.com/hareku/evdev-trigger/pkg/config"
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+)
+
+// absState is the state of an absolute trigger.
+type absState struct {
+	// known is false until the first value of the axis.
+	known bool
+	// armed is true if the trigger can fire, the value went out beyond the hysteresis since the last run.
+	armed bool
+}
+
+// observe updates s by v, and reports whether v fires the trigger c.
+// The first value only arms the trigger, since its previous value is unknown.
+func (s *absState) observe(c config.AbsoluteConfig, v float64) bool {
+	if !s.known {
+		s.known = true
+		s.armed = !c.Inside(v)
+		return false
+	}
+	if c.Outside(v) {
+		s.armed = true
+	}
+	if s.armed && c.Inside(v) {
+		s.armed = false
+		return true
+	}
+	return false
+}
+
+// loadAbsInfo reads the absinfo of the axes of the absolute triggers from the connected device.
+// The current values of the axes are the first values of the triggers.
+func (h *handler) loadAbsInfo() {
+	h.absInfo = make(map[uint16]evdev.AbsInfo)
+	h.abs = make([]absState, len(h.bindings.Absolute))
+	if h.device == nil {
+		return
+	}
+	for i, c := range h.bindings.Absolute {
+		code := uint16(c.Axis)
+		info, ok := h.absInfo[code]
+		if !ok {
+			var err error
+			if info, err = h.device.AbsInfo(code); err != nil {
+				h.logger.Errorf("%s", err)
+				continue
+			}
+			h.absInfo[code] = info
+		}
+		if v, ok := h.absValue(c, info.Value); ok {
+			h.abs[i].observe(c, v)
+		}
+	}
+}
+
+// absValue returns the value of the trigger c, which is normalized if c requires.
+func (h *handler) absValue(c config.AbsoluteConfig, value int32) (float64, bool) {
+	if !c.Normalize {
+		return float64(value), true
+	}
+	info, ok := h.absInfo[uint16(c.Axis)]
+	if !ok || info.Maximum <= info.Minimum {
+		return 0, false
+	}
+	return float64(value-info.Minimum) / float64(info.Maximum-info.Minimum), true
+}
+
+// matchAbsolute returns the absolute triggers fired by ev.
+func (h *handler) matchAbsolute(ev *evdev.InputEvent) []namedTrigger {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var triggers []namedTrigger
+	for i, c := range h.bindings.Absolute {
+		if uint16(c.Axis) != ev.Code {
+			continue
+		}
+		v, ok := h.absValue(c, ev.Value)
+		if !ok {
+			h.logger.Debugf("Skipped absolute %s, the range of the axis is unknown", c.Name())
+			continue
+		}
+		t := newTrigger(absoluteTriggerName(c), c.CommandConfig, ev)
+		if h.abs[i].observe(c, v) && h.ready(t) {
+			triggers = append(triggers, t)
+		}
+	}
+	return triggers
+}
+
+func absoluteTriggerName(c config.AbsoluteConfig) string {
+	return "absolute " + c.Name()
+}
@@ -0,0 +1,48 @@
+package watch
+
+import "github.com/hareku/evdev-trigger/pkg/config"
+
+// chordHas reports whether code is one of the keys of c.
+func chordHas(c config.ChordConfig, code uint16) bool {
+	for _, k := range c.Keys {
+		if k.Has(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// satisfies reports whether the pressed keys complete the chord c.
+func (s *keyState) satisfies(c config.ChordConfig) bool {
+	for _, k := range c.Keys {
+		pressed := false
+		for _, code := range k.Codes {
+			if s.isPressed(code) {
+				pressed = true
+				break
+			}
+		}
+		if !pressed {
+			return false
+		}
+	}
+
+	if c.Strictness == config.StrictnessSuperset {
+		return true
+	}
+	for code := range s.pressed {
+		if !chordHas(c, code) {
+			return false
+		}
+	}
+	return true
+}
+
+// chordCodes returns all codes of the keys of c.
+func chordCodes(c config.ChordConfig) []uint16 {
+	var codes []uint16
+	for _, k := range c.Keys {
+		codes = append(codes, k.Codes...)
+	}
+	return codes
+}
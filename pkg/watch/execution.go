@@ -0,0 +1,152 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/hareku/evdev-trigger/pkg/config"
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+)
+
+// execution is a run of the command of a trigger.
+type execution struct {
+	ctx     context.Context
+	trigger namedTrigger
+	device  evdev.DeviceInfo
+	mode    string
+	pressed []uint16
+	// cancel stops the command for the restart.
+	cancel    context.CancelFunc
+	restarted bool
+}
+
+// exec runs the command of t by the runner.
+// If the commands of t reach its concurrency, the overlap policy of t decides the run.
+func (h *handler) exec(ctx context.Context, t namedTrigger) {
+	e := &execution{trigger: t}
+	// every run is cancelable, since a trigger of the same name may restart it after a reload.
+	e.ctx, e.cancel = context.WithCancel(ctx)
+	overlap := t.cmd.OverlapOrDefault()
+
+	h.mu.Lock()
+	e.device = h.info
+	e.mode = h.mode
+	e.pressed = h.keys.pressedCodes()
+	running := h.running[t.name]
+	// the runs stopped for the restart do not count, while they wait for the grace period.
+	var live []*execution
+	for _, r := range running {
+		if !r.restarted {
+			live = append(live, r)
+		}
+	}
+	if limit := t.cmd.ConcurrencyOrDefault(); limit > 0 && len(live) >= limit {
+		switch overlap {
+		case config.OverlapDrop:
+			h.logger.Infof("Dropped %s, %d of its commands are running", t.name, len(live))
+			h.mu.Unlock()
+			e.cancel()
+			return
+		case config.OverlapRestart:
+			h.logger.Infof("Restarting %s, %d of its commands are running", t.name, len(live))
+			// the oldest runs stop, so that the new run is within the limit.
+			for _, old := range live[:len(live)-limit+1] {
+				old.restarted = true
+				old.cancel()
+			}
+		default:
+			h.logger.Debugf("Queued %s, %d of its commands are running", t.name, len(live))
+			h.waiting[t.name] = append(h.waiting[t.name], e)
+			h.mu.Unlock()
+			return
+		}
+	}
+	h.running[t.name] = append(running, e)
+	h.mu.Unlock()
+	h.start(e)
+}
+
+func (h *handler) start(e *execution) {
+	h.runner.Go(func() {
+		h.run(e)
+		h.finish(e)
+	})
+}
+
+// finish removes e from the running commands, and starts the next waiting command of the trigger.
+func (h *handler) finish(e *execution) {
+	e.cancel()
+	name := e.trigger.name
+
+	h.mu.Lock()
+	running := h.running[name]
+	for i, r := range running {
+		if r == e {
+			running = append(running[:i:i], running[i+1:]...)
+			break
+		}
+	}
+	var next *execution
+	if w := h.waiting[name]; len(w) > 0 {
+		next = w[0]
+		h.waiting[name] = w[1:]
+		running = append(running, next)
+	} else {
+		delete(h.waiting, name)
+	}
+	if len(running) == 0 {
+		delete(h.running, name)
+	} else {
+		h.running[name] = running
+	}
+	h.mu.Unlock()
+
+	if next != nil {
+		h.start(next)
+	}
+}
+
+func (h *handler) run(e *execution) {
+	ctx := e.ctx
+	timeout := e.trigger.cmd.Timeout
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := e.trigger.cmd.Command
+	b, err := h.executor.Do(ctx, Job{
+		Command: cmd,
+		Script:  e.trigger.cmd.IsScript(),
+		Trigger: e.trigger.name,
+		Event:   e.trigger.ev,
+		Device:  e.device,
+		Delta:   e.trigger.delta,
+		Mode:    e.mode,
+		Pressed: e.pressed,
+		Stdin:   e.trigger.cmd.Stdin,
+		Process: e.trigger.cmd.ProcessConfig,
+	})
+	cmdStr := strings.Join(cmd, " ")
+	if err != nil {
+		h.mu.Lock()
+		restarted := e.restarted
+		h.mu.Unlock()
+		switch {
+		case restarted:
+			h.logger.Infof("Command %q stopped for the restart", cmdStr)
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			h.logger.Errorf("Command %q timed out after %v", cmdStr, timeout)
+		default:
+			h.logger.Errorf("Command %q failed: %s", cmdStr, err)
+		}
+		return
+	}
+	if len(b) > 0 {
+		h.logger.Infof("Command %q succeeded: %s", cmdStr, string(b))
+		return
+	}
+	h.logger.Infof("Command %q succeeded, no output", cmdStr)
+}
@@ -1,31 +1,202 @@
 package watch
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"os/exec"
+	"syscall"
+	"time"
 
 	"github.com/hareku/evdev-trigger/pkg/config"
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"github.com/hareku/evdev-trigger/pkg/session"
 )
 
 //go:generate mockgen -source=${GOFILE} -destination=./${GOPACKAGE}mock/mock_${GOFILE} -package=${GOPACKAGE}mock
 
+// DefaultGracePeriod is the default time from SIGTERM to SIGKILL for stopping commands.
+const DefaultGracePeriod = 3 * time.Second
+
 type Executor interface {
-	Do(ctx context.Context, cmd config.Command) ([]byte, error)
+	// Do runs the command of job and returns its output.
+	// The placeholders in the arguments are replaced by the data of job,
+	// and the environment variables of job are added to the environment of the process.
+	// When ctx is done, the process group of the command gets SIGTERM, and SIGKILL after the grace period.
+	Do(ctx context.Context, job Job) ([]byte, error)
 }
 
-type executor struct{}
+// Job is a command to run with the trigger which fired it.
+type Job struct {
+	Command config.Command
+	// Script is true if Command is written as a string, whose placeholders are not replaced.
+	Script bool
+	// Trigger is the name of the trigger.
+	Trigger string
+	// Event is the event which fired the trigger.
+	// The switch triggers fired on connection have an event made from the state of the switch.
+	Event *evdev.InputEvent
+	// Device is the device of the trigger.
+	Device evdev.DeviceInfo
+	// Delta is the delta consumed by a relative trigger.
+	Delta int32
+	// Mode is the active mode when the trigger fired.
+	Mode string
+	// Pressed is the codes of the keys held when the trigger fired.
+	Pressed []uint16
+	// Stdin is what the command reads from its stdin.
+	Stdin config.Stdin
+	// Process is the working directory, the environment and the user of the command.
+	Process config.ProcessConfig
+}
 
-func NewExecutor() Executor {
-	return &executor{}
+// Data returns the data of the placeholders in the arguments of the command of j.
+func (j Job) Data() config.CommandData {
+	d := config.CommandData{
+		Trigger:    j.Trigger,
+		Delta:      j.Delta,
+		DeviceName: j.Device.Name,
+		DevicePhys: j.Device.Phys,
+		DevicePath: j.Device.Path,
+	}
+	if ev := j.Event; ev != nil {
+		d.Type = evdev.TypeName(ev.Type)
+		d.Code = ev.Code
+		d.CodeName = evdev.CodeName(ev.Type, ev.Code)
+		d.Value = ev.Value
+		d.Time = ev.Timestamp()
+	}
+	return d
 }
 
-func (e *executor) Do(ctx context.Context, cmd config.Command) ([]byte, error) {
-	var ecmd *exec.Cmd
-	if len(cmd) == 1 {
-		ecmd = exec.CommandContext(ctx, cmd[0])
-	} else {
-		ecmd = exec.CommandContext(ctx, cmd[0], cmd[1:]...)
+// Environ returns the environment variables which describe the trigger, the event and the device of j.
+func (j Job) Environ() []string {
+	env := []string{
+		"EVDEV_TRIGGER=" + j.Trigger,
+		"EVDEV_DEVICE_NAME=" + j.Device.Name,
+		"EVDEV_DEVICE_PHYS=" + j.Device.Phys,
+		"EVDEV_DEVICE_PATH=" + j.Device.Path,
 	}
+	if ev := j.Event; ev != nil {
+		env = append(env,
+			"EVDEV_TYPE="+evdev.TypeName(ev.Type),
+			fmt.Sprintf("EVDEV_CODE=%d", ev.Code),
+			"EVDEV_CODE_NAME="+evdev.CodeName(ev.Type, ev.Code),
+			fmt.Sprintf("EVDEV_VALUE=%d", ev.Value),
+			fmt.Sprintf("EVDEV_TIME=%d.%06d", ev.Time.Sec, ev.Time.Usec),
+		)
+		if ev.Type == evdev.EV_REL {
+			env = append(env, fmt.Sprintf("EVDEV_DELTA=%d", j.Delta))
+		}
+	}
+	return env
+}
+
+type NewExecutorInput struct {
+	// GracePeriod is the time from SIGTERM to SIGKILL, DefaultGracePeriod if zero.
+	GracePeriod time.Duration
+	// Sessions finds the session of the commands with session: active-user, the one of logind if nil.
+	Sessions session.Finder
+}
 
-	return ecmd.Output()
+type executor struct {
+	grace    time.Duration
+	sessions session.Finder
+}
+
+func NewExecutor(in NewExecutorInput) Executor {
+	grace := in.GracePeriod
+	if grace <= 0 {
+		grace = DefaultGracePeriod
+	}
+	sessions := in.Sessions
+	if sessions == nil {
+		sessions = session.NewFinder(session.NewFinderInput{})
+	}
+	return &executor{grace: grace, sessions: sessions}
+}
+
+func (e *executor) Do(ctx context.Context, job Job) ([]byte, error) {
+	cmd := job.Command
+	if !job.Script {
+		var err error
+		if cmd, err = cmd.Render(job.Data()); err != nil {
+			return nil, err
+		}
+	}
+	if len(cmd) == 0 {
+		return nil, errors.New("command is empty")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p, sessionEnv, err := e.session(job.Process)
+	if err != nil {
+		return nil, err
+	}
+	cred, userEnv, err := credential(p)
+	if err != nil {
+		return nil, err
+	}
+	userEnv = append(userEnv, sessionEnv...)
+	// the program is found in the directory and PATH of the command, not of evdev-trigger.
+	path, err := config.LookPath(cmd[0], job.Process)
+	if err != nil {
+		return nil, err
+	}
+	ecmd := exec.Command(path, cmd[1:]...)
+	ecmd.Args[0] = cmd[0]
+	ecmd.Dir = job.Process.Dir
+	ecmd.Env = environ(job, userEnv)
+	if job.Stdin == config.StdinEventJSON {
+		b, err := job.EventJSON()
+		if err != nil {
+			return nil, err
+		}
+		ecmd.Stdin = bytes.NewReader(b)
+	}
+	// the command and its children are in their own process group, so that all of them are stopped together.
+	ecmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Credential: cred}
+	var stdout, stderr bytes.Buffer
+	ecmd.Stdout = &stdout
+	ecmd.Stderr = &stderr
+	if err := ecmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-done:
+		case <-ctx.Done():
+			e.stop(ecmd.Process.Pid, done)
+		}
+	}()
+	err = ecmd.Wait()
+	close(done)
+	<-stopped
+
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		ee.Stderr = stderr.Bytes()
+	}
+	return stdout.Bytes(), err
+}
+
+// stop sends SIGTERM to the process group pgid, and SIGKILL when the command exits or the grace period passes.
+// done is closed when the command exits and closes its output.
+// SIGKILL also stops the children which ignored SIGTERM after the command exits.
+func (e *executor) stop(pgid int, done <-chan struct{}) {
+	_ = syscall.Kill(-pgid, syscall.SIGTERM)
+	timer := time.NewTimer(e.grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+	}
+	_ = syscall.Kill(-pgid, syscall.SIGKILL)
 }
@@ -0,0 +1,210 @@
+package watch_test
+
+import (
+	"context"
+	"os"
+	"os/user"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hareku/evdev-trigger/pkg/config"
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"github.com/hareku/evdev-trigger/pkg/session"
+	"github.com/hareku/evdev-trigger/pkg/session/sessionmock"
+	"github.com/hareku/evdev-trigger/pkg/watch"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_executor_Do(t *testing.T) {
+	e := watch.NewExecutor(watch.NewExecutorInput{})
+	b, err := e.Do(context.Background(), watch.Job{
+		Command: config.Command{"sh", "-c", "echo $EVDEV_TRIGGER $EVDEV_DELTA"},
+		Trigger: "relative REL_WHEEL",
+		Event:   &evdev.InputEvent{Type: evdev.EV_REL, Code: 8, Value: 1},
+		Delta:   3,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "relative REL_WHEEL 3\n", string(b))
+}
+
+func Test_executor_Do_Placeholders(t *testing.T) {
+	e := watch.NewExecutor(watch.NewExecutorInput{})
+	b, err := e.Do(context.Background(), watch.Job{
+		Command: config.Command{"echo", "pressed {{.CodeName}} value {{.Value}} on {{.DeviceName}}"},
+		Event:   &evdev.InputEvent{Type: evdev.EV_KEY, Code: 115, Value: 1},
+		Device:  evdev.DeviceInfo{Name: "Keyboard"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "pressed KEY_VOLUMEUP value 1 on Keyboard\n", string(b))
+
+	// the commands written as strings are not rendered, so the values cannot inject commands into the shell.
+	b, err = e.Do(context.Background(), watch.Job{
+		Command: config.Command{"sh", "-c", "echo '{{.DeviceName}}'"},
+		Script:  true,
+		Device:  evdev.DeviceInfo{Name: "'; id; '"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "{{.DeviceName}}\n", string(b))
+}
+
+func TestJob_Environ(t *testing.T) {
+	job := watch.Job{
+		Trigger: "key KEY_VOLUMEUP",
+		Event: &evdev.InputEvent{
+			Time:  syscall.Timeval{Sec: 12, Usec: 34},
+			Type:  evdev.EV_KEY,
+			Code:  115,
+			Value: 1,
+		},
+		Device: evdev.DeviceInfo{Path: "/dev/input/event3", Name: "Keyboard", Phys: "usb-1/input0"},
+	}
+	require.Equal(t, []string{
+		"EVDEV_TRIGGER=key KEY_VOLUMEUP",
+		"EVDEV_DEVICE_NAME=Keyboard",
+		"EVDEV_DEVICE_PHYS=usb-1/input0",
+		"EVDEV_DEVICE_PATH=/dev/input/event3",
+		"EVDEV_TYPE=EV_KEY",
+		"EVDEV_CODE=115",
+		"EVDEV_CODE_NAME=KEY_VOLUMEUP",
+		"EVDEV_VALUE=1",
+		"EVDEV_TIME=12.000034",
+	}, job.Environ())
+
+	job.Event = nil
+	require.NotContains(t, job.Environ(), "EVDEV_VALUE=1")
+}
+
+func Test_executor_Do_Stop(t *testing.T) {
+	e := watch.NewExecutor(watch.NewExecutorInput{GracePeriod: 200 * time.Millisecond})
+
+	// the children in the process group stop together, or Do waits for their output.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := e.Do(ctx, watch.Job{Command: config.Command{"sh", "-c", "sleep 10 & sleep 10; wait"}})
+	require.Error(t, err)
+	require.Less(t, int64(time.Since(start)), int64(2*time.Second))
+
+	// commands which ignore SIGTERM are killed after the grace period.
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = e.Do(ctx, watch.Job{Command: config.Command{"sh", "-c", `trap "" TERM; sleep 10`}})
+	require.Error(t, err)
+	require.GreaterOrEqual(t, int64(time.Since(start)), int64(300*time.Millisecond))
+	require.Less(t, int64(time.Since(start)), int64(2*time.Second))
+}
+
+func TestJob_EventJSON(t *testing.T) {
+	job := watch.Job{
+		Trigger: "chord KEY_LEFTCTRL+KEY_T",
+		Event: &evdev.InputEvent{
+			Time:  syscall.Timeval{Sec: 1609459200},
+			Type:  evdev.EV_KEY,
+			Code:  20,
+			Value: 1,
+		},
+		Device:  evdev.DeviceInfo{Path: "/dev/input/event3", Name: "Keyboard", Vendor: 1},
+		Mode:    config.ModeDefault,
+		Pressed: []uint16{20, 29},
+	}
+	b, err := job.EventJSON()
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"version": 1,
+		"trigger": {"name": "chord KEY_LEFTCTRL+KEY_T", "mode": "default", "delta": 0},
+		"event": {"type": "EV_KEY", "code": 20, "code_name": "KEY_T", "value": 1, "time": "`+time.Unix(1609459200, 0).Format(time.RFC3339Nano)+`"},
+		"device": {"name": "Keyboard", "phys": "", "uniq": "", "path": "/dev/input/event3", "vendor": 1, "product": 0},
+		"keys": {"pressed": ["KEY_T", "KEY_LEFTCTRL"]}
+	}`, string(b))
+}
+
+func Test_executor_Do_Stdin(t *testing.T) {
+	e := watch.NewExecutor(watch.NewExecutorInput{})
+	job := watch.Job{Command: config.Command{"cat"}, Trigger: "KEY_A"}
+	b, err := e.Do(context.Background(), job)
+	require.NoError(t, err)
+	require.Empty(t, b)
+
+	job.Stdin = config.StdinEventJSON
+	b, err = e.Do(context.Background(), job)
+	require.NoError(t, err)
+	want, err := job.EventJSON()
+	require.NoError(t, err)
+	require.Equal(t, want, b)
+}
+
+func Test_executor_Do_Process(t *testing.T) {
+	e := watch.NewExecutor(watch.NewExecutorInput{})
+	dir := t.TempDir()
+	require.NoError(t, os.Setenv("EVDEV_TRIGGER_TEST", "inherited"))
+	defer os.Unsetenv("EVDEV_TRIGGER_TEST")
+	b, err := e.Do(context.Background(), watch.Job{
+		Command: config.Command{"/bin/sh", "-c", `pwd; echo "${A-unset} ${EVDEV_TRIGGER_TEST-unset} $EVDEV_TRIGGER"`},
+		Trigger: "KEY_A",
+		Process: config.ProcessConfig{
+			Dir: dir,
+			Env: config.EnvConfig{Inherit: new(bool), Set: map[string]string{"A": "a"}},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, dir+"\na unset KEY_A\n", string(b))
+
+	// the program is found in the directory and PATH of the command.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "run.sh"), []byte("#!/bin/sh\necho \"$0 $1\"\n"), 0o755))
+	b, err = e.Do(context.Background(), watch.Job{
+		Command: config.Command{"./run.sh", "a"},
+		Process: config.ProcessConfig{Dir: dir},
+	})
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "run.sh")+" a\n", string(b))
+	b, err = e.Do(context.Background(), watch.Job{
+		Command: config.Command{"run.sh", "b"},
+		Process: config.ProcessConfig{Env: config.EnvConfig{Set: map[string]string{"PATH": dir}}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(dir, "run.sh")+" b\n", string(b))
+
+	if os.Getuid() != 0 {
+		t.Skip("running as another user requires root")
+	}
+	b, err = e.Do(context.Background(), watch.Job{
+		Command: config.Command{"/bin/sh", "-c", `echo "$(id -u) $USER"`},
+		Process: config.ProcessConfig{User: "nobody"},
+	})
+	require.NoError(t, err)
+	u, err := user.Lookup("nobody")
+	require.NoError(t, err)
+	require.Equal(t, u.Uid+" nobody\n", string(b))
+}
+
+func Test_executor_Do_Session(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	sessions := sessionmock.NewMockFinder(ctrl)
+	e := watch.NewExecutor(watch.NewExecutorInput{Sessions: sessions})
+
+	u, err := user.Current()
+	require.NoError(t, err)
+	sessions.EXPECT().ActiveUser().Times(1).Return(session.Session{
+		ID:   "3",
+		UID:  u.Uid,
+		User: u.Username,
+		Env:  []string{"WAYLAND_DISPLAY=wayland-0"},
+	}, nil)
+	b, err := e.Do(context.Background(), watch.Job{
+		Command: config.Command{"/bin/sh", "-c", `echo "$(id -u) $USER $WAYLAND_DISPLAY"`},
+		Process: config.ProcessConfig{Session: config.SessionActiveUser},
+	})
+	require.NoError(t, err)
+	require.Equal(t, u.Uid+" "+u.Username+" wayland-0\n", string(b))
+
+	sessions.EXPECT().ActiveUser().Times(1).Return(session.Session{}, session.ErrNoActiveSession)
+	_, err = e.Do(context.Background(), watch.Job{
+		Command: config.Command{"true"},
+		Process: config.ProcessConfig{Session: config.SessionActiveUser},
+	})
+	require.ErrorIs(t, err, session.ErrNoActiveSession)
+}
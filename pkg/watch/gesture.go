@@ -0,0 +1,97 @@
+package watch
+
+import (
+	"math"
+
+	"github.com/hareku/evdev-trigger/pkg/config"
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"github.com/hareku/evdev-trigger/pkg/gesture"
+)
+
+// matchGestures feeds ev to the gesture recognizer and returns the gesture triggers fired by the recognized stroke.
+func (h *handler) matchGestures(ev *evdev.InputEvent) []namedTrigger {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.bindings.Gestures) == 0 {
+		return nil
+	}
+	s, ok := h.gestures.Feed(ev)
+	if !ok {
+		return nil
+	}
+	h.logger.Debugf("Got stroke of %d fingers for %v (dx %.3f, dy %.3f, spread %.3f, travel %.3f)",
+		s.Fingers, s.Duration, s.DX, s.DY, s.Spread, s.Travel)
+
+	var triggers []namedTrigger
+	for _, c := range h.bindings.Gestures {
+		if !gestureMatches(c, s) {
+			continue
+		}
+		t := newTrigger(gestureTriggerName(c), c.CommandConfig, ev)
+		if h.ready(t) {
+			triggers = append(triggers, t)
+		}
+	}
+	return triggers
+}
+
+// loadGestureRange reads the ranges of positions from the connected device for the gesture recognizer.
+func (h *handler) loadGestureRange() {
+	if h.device == nil || len(h.bindings.Gestures) == 0 {
+		return
+	}
+	x, err := h.device.AbsInfo(evdev.ABS_MT_POSITION_X)
+	if err != nil {
+		h.logger.Errorf("%s", err)
+		return
+	}
+	y, err := h.device.AbsInfo(evdev.ABS_MT_POSITION_Y)
+	if err != nil {
+		h.logger.Errorf("%s", err)
+		return
+	}
+	h.gestures.SetRange(x, y)
+}
+
+// gestureMatches reports whether the stroke s is the gesture of c.
+func gestureMatches(c config.GestureConfig, s gesture.Stroke) bool {
+	if s.Fingers != c.FingersOrDefault() {
+		return false
+	}
+	if timeout := c.TimeoutOrDefault(); timeout > 0 && s.Duration > timeout {
+		return false
+	}
+	distance := c.DistanceOrDefault()
+	switch c.Gesture {
+	case config.GestureSwipe:
+		// the longer axis of the movement decides the direction.
+		var dir config.GestureDirection
+		var d float64
+		if math.Abs(s.DX) >= math.Abs(s.DY) {
+			dir, d = config.GestureRight, s.DX
+			if s.DX < 0 {
+				dir = config.GestureLeft
+			}
+		} else {
+			dir, d = config.GestureDown, s.DY
+			if s.DY < 0 {
+				dir = config.GestureUp
+			}
+		}
+		return dir == c.Direction && math.Abs(d) >= distance
+	case config.GesturePinch:
+		dir := config.GestureOut
+		if s.Spread < 0 {
+			dir = config.GestureIn
+		}
+		return dir == c.Direction && math.Abs(s.Spread) >= distance
+	case config.GestureTap:
+		return s.Travel <= distance
+	}
+	return false
+}
+
+func gestureTriggerName(c config.GestureConfig) string {
+	return "gesture " + c.Name()
+}
@@ -0,0 +1,175 @@
+package watch
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hareku/evdev-trigger/pkg/config"
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+)
+
+// Group runs a watcher for each device.
+type Group interface {
+	Run(ctx context.Context) error
+	// Apply replaces the devices with reloaded ones.
+	// Triggers of a running device are replaced in place,
+	// and a device whose matcher changed is watched again by the new matcher.
+	Apply(devices []config.DeviceConfig)
+}
+
+type NewGroupInput struct {
+	Devices       []config.DeviceConfig
+	Logger        Logger
+	Finder        evdev.Finder
+	Executor      Executor
+	Runner        Runner
+	ReconnectCond *sync.Cond
+}
+
+func NewGroup(in NewGroupInput) Group {
+	return &group{
+		devices:  in.Devices,
+		logger:   in.Logger,
+		finder:   in.Finder,
+		executor: in.Executor,
+		runner:   in.Runner,
+		cnd:      in.ReconnectCond,
+		members:  make(map[string]*member),
+		errCh:    make(chan error, 1),
+	}
+}
+
+type group struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	devices  []config.DeviceConfig
+	logger   Logger
+	finder   evdev.Finder
+	executor Executor
+	runner   Runner
+	cnd      *sync.Cond
+	members  map[string]*member
+	errCh    chan error
+}
+
+// member is a running watcher of a device.
+type member struct {
+	conf    config.DeviceConfig
+	logger  Logger
+	handler Handler
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+func (g *group) Run(ctx context.Context) error {
+	g.mu.Lock()
+	g.ctx = ctx
+	g.apply(g.devices)
+	g.mu.Unlock()
+
+	var err error
+	select {
+	case <-ctx.Done():
+		err = ctx.Err()
+	case err = <-g.errCh:
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, m := range g.members {
+		g.stop(m)
+	}
+	g.members = make(map[string]*member)
+	return err
+}
+
+func (g *group) Apply(devices []config.DeviceConfig) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.devices = devices
+	if g.ctx == nil {
+		// not running yet, Run starts the devices.
+		return
+	}
+	g.apply(devices)
+}
+
+func (g *group) apply(devices []config.DeviceConfig) {
+	next := make(map[string]*member, len(devices))
+	for _, d := range devices {
+		key := d.Label()
+		m, ok := g.members[key]
+		switch {
+		case !ok:
+			// the prefix does not depend on the number of devices, which changes on reloading.
+			l := WithPrefix(g.logger, key)
+			m = &member{
+				conf:   d,
+				logger: l,
+				handler: NewHandler(NewHandlerInput{
+					Logger:   l,
+					Executor: g.executor,
+					Runner:   g.runner,
+					Bindings: d.Bindings,
+				}),
+			}
+			g.start(m)
+			m.logger.Debugf("Started watching %s", d.Match)
+		case m.conf.Match != d.Match:
+			m.logger.Infof("Device matcher changed from %s to %s", m.conf.Match, d.Match)
+			g.stop(m)
+			m.conf = d
+			m.handler.Update(d.Bindings)
+			g.start(m)
+		default:
+			m.conf = d
+			m.handler.Update(d.Bindings)
+			m.logger.Debugf("Updated triggers")
+		}
+		next[key] = m
+	}
+
+	for key, m := range g.members {
+		if _, ok := next[key]; !ok {
+			g.stop(m)
+			m.logger.Infof("Stopped watching %s", m.conf.Match)
+		}
+	}
+	g.members = next
+}
+
+func (g *group) start(m *member) {
+	ctx, cancel := context.WithCancel(g.ctx)
+	m.cancel = cancel
+	m.done = make(chan struct{})
+
+	w := NewWatcher(NewWatcherInput{
+		Matcher:       m.conf.Match,
+		Logger:        m.logger,
+		Finder:        g.finder,
+		Handler:       m.handler,
+		ReconnectCond: g.cnd,
+	})
+	go func() {
+		defer close(m.done)
+		if err := w.Run(ctx); err != nil && ctx.Err() == nil {
+			select {
+			case g.errCh <- err:
+			default:
+			}
+		}
+	}()
+}
+
+// stop cancels the watcher of m and waits until it returns.
+func (g *group) stop(m *member) {
+	m.cancel()
+
+	// wake up the watcher waiting for device connection.
+	g.cnd.L.Lock()
+	g.cnd.Broadcast()
+	g.cnd.L.Unlock()
+
+	<-m.done
+}
@@ -0,0 +1,217 @@
+package watch_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hareku/evdev-trigger/pkg/config"
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"github.com/hareku/evdev-trigger/pkg/evdev/evdevmock"
+	"github.com/hareku/evdev-trigger/pkg/watch"
+	"github.com/hareku/evdev-trigger/pkg/watch/watchmock"
+	"github.com/stretchr/testify/require"
+)
+
+// newChanDevice returns a device which reads events from the returned channel until it is closed.
+func newChanDevice(ctrl *gomock.Controller) (*evdevmock.MockDevice, chan<- *evdev.InputEvent) {
+	events := make(chan *evdev.InputEvent)
+	closed := make(chan struct{})
+
+	device := evdevmock.NewMockDevice(ctrl)
+	device.EXPECT().Info().AnyTimes()
+	device.EXPECT().Read().AnyTimes().DoAndReturn(func() (*evdev.InputEvent, error) {
+		select {
+		case ev := <-events:
+			return ev, nil
+		case <-closed:
+			return nil, errors.New("closed")
+		}
+	})
+	device.EXPECT().Close().Times(1).DoAndReturn(func() error {
+		close(closed)
+		return nil
+	})
+	return device, events
+}
+
+func runGroup(t *testing.T, g watch.Group) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- g.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		require.ErrorIs(t, <-done, context.Canceled)
+	})
+}
+
+func expectCommand(ctrl *gomock.Controller, executor *watchmock.MockExecutor, cmd config.Command) <-chan struct{} {
+	ran := make(chan struct{})
+	executor.EXPECT().Do(gomock.Any(), jobOf(cmd)).Times(1).DoAndReturn(func(context.Context, watch.Job) ([]byte, error) {
+		close(ran)
+		return nil, nil
+	})
+	return ran
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func Test_group_Apply_UpdateTriggers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	matcher := evdev.Matcher{Phys: "00-00-00-00-00"}
+	keyUp := &evdev.InputEvent{Type: evdev.EV_KEY, Code: 10, Value: 0}
+
+	device, events := newChanDevice(ctrl)
+	finder := evdevmock.NewMockFinder(ctrl)
+	finder.EXPECT().Find(matcher).Times(1).Return(device, nil)
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	g := watch.NewGroup(watch.NewGroupInput{
+		Devices: []config.DeviceConfig{{
+			Name:  "keyboard",
+			Match: matcher,
+			Bindings: config.Bindings{Triggers: config.Triggers{
+				10: {{CommandConfig: config.CommandConfig{Command: config.Command{"echo", "A"}}}},
+			}},
+		}},
+		Logger:        watch.NewLogger(io.Discard, true),
+		Finder:        finder,
+		Executor:      executor,
+		Runner:        watch.NewRunner(0),
+		ReconnectCond: sync.NewCond(new(sync.Mutex)),
+	})
+	runGroup(t, g)
+
+	ran := expectCommand(ctrl, executor, config.Command{"echo", "A"})
+	events <- keyUp
+	waitFor(t, ran)
+
+	g.Apply([]config.DeviceConfig{{
+		Name:  "keyboard",
+		Match: matcher,
+		Bindings: config.Bindings{Triggers: config.Triggers{
+			10: {{CommandConfig: config.CommandConfig{Command: config.Command{"echo", "B"}}}},
+		}},
+	}})
+
+	ran = expectCommand(ctrl, executor, config.Command{"echo", "B"})
+	events <- keyUp
+	waitFor(t, ran)
+}
+
+func Test_group_Apply_ChangeMatcher(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	matcher1 := evdev.Matcher{Phys: "00-00-00-00-00"}
+	matcher2 := evdev.Matcher{Name: "Foot Pedal"}
+
+	device1, _ := newChanDevice(ctrl)
+	device2, events2 := newChanDevice(ctrl)
+	connected := make(chan struct{})
+	finder := evdevmock.NewMockFinder(ctrl)
+	gomock.InOrder(
+		finder.EXPECT().Find(matcher1).Times(1).DoAndReturn(func(evdev.Matcher) (evdev.Device, error) {
+			close(connected)
+			return device1, nil
+		}),
+		finder.EXPECT().Find(matcher2).Times(1).Return(device2, nil),
+	)
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	bindings := config.Bindings{Triggers: config.Triggers{
+		10: {{CommandConfig: config.CommandConfig{Command: config.Command{"echo", "A"}}}},
+	}}
+	g := watch.NewGroup(watch.NewGroupInput{
+		Devices: []config.DeviceConfig{{
+			Name:     "pedal",
+			Match:    matcher1,
+			Bindings: bindings,
+		}},
+		Logger:        watch.NewLogger(io.Discard, true),
+		Finder:        finder,
+		Executor:      executor,
+		Runner:        watch.NewRunner(0),
+		ReconnectCond: sync.NewCond(new(sync.Mutex)),
+	})
+	runGroup(t, g)
+	waitFor(t, connected)
+
+	g.Apply([]config.DeviceConfig{{
+		Name:     "pedal",
+		Match:    matcher2,
+		Bindings: bindings,
+	}})
+
+	ran := expectCommand(ctrl, executor, config.Command{"echo", "A"})
+	events2 <- &evdev.InputEvent{Type: evdev.EV_KEY, Code: 10, Value: 0}
+	waitFor(t, ran)
+}
+
+// syncBuffer is a buffer written by the loggers of the devices at once.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func Test_group_Apply_LogPrefix(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	keyboard := evdev.Matcher{Phys: "00-00-00-00-00"}
+	pedal := evdev.Matcher{Phys: "11-11-11-11-11"}
+
+	device, events := newChanDevice(ctrl)
+	finder := evdevmock.NewMockFinder(ctrl)
+	finder.EXPECT().Find(keyboard).Times(1).Return(device, nil)
+	finder.EXPECT().Find(pedal).AnyTimes().Return(nil, evdev.ErrDeviceNotFound)
+
+	var logs syncBuffer
+	g := watch.NewGroup(watch.NewGroupInput{
+		Devices:       []config.DeviceConfig{{Name: "keyboard", Match: keyboard}},
+		Logger:        watch.NewLogger(&logs, true),
+		Finder:        finder,
+		Executor:      watchmock.NewMockExecutor(ctrl),
+		Runner:        watch.NewRunner(0),
+		ReconnectCond: sync.NewCond(new(sync.Mutex)),
+	})
+	runGroup(t, g)
+	require.Eventually(t, func() bool {
+		return strings.Contains(logs.String(), "Started watching")
+	}, time.Second, time.Millisecond*10)
+
+	// the device started alone keeps its prefix after another device is added.
+	g.Apply([]config.DeviceConfig{{Name: "keyboard", Match: keyboard}, {Name: "pedal", Match: pedal}})
+	events <- &evdev.InputEvent{Type: evdev.EV_KEY, Code: 10, Value: 0}
+	require.Eventually(t, func() bool {
+		return strings.Contains(logs.String(), "keyboard: Trigger not found")
+	}, time.Second, time.Millisecond*10)
+
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		require.Regexp(t, `\]: (keyboard|pedal): `, line)
+	}
+}
@@ -2,78 +2,615 @@ package watch
 
 import (
 	"context"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/hareku/evdev-trigger/pkg/config"
 	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"github.com/hareku/evdev-trigger/pkg/gesture"
 )
 
 //go:generate mockgen -source=${GOFILE} -destination=./${GOPACKAGE}mock/mock_${GOFILE} -package=${GOPACKAGE}mock
 
 type Handler interface {
 	Do(ctx context.Context, ev *evdev.InputEvent)
+	// Update replaces the bindings.
+	// The interval state of triggers which still exist is kept.
+	Update(bindings config.Bindings)
+	// Connect is called when the device d is connected, before its events.
+	// It runs the triggers of switches which changed while the device was disconnected.
+	Connect(ctx context.Context, d evdev.Device)
+	// Reset forgets the held keys and the active mode, and stops the pending triggers.
+	// It is called when the device is disconnected.
+	Reset()
 }
 
 type NewHandlerInput struct {
 	Logger   Logger
 	Executor Executor
-	Triggers map[uint16]config.CommandConfig
+	// Runner runs the commands, which may be shared with other handlers.
+	Runner   Runner
+	Bindings config.Bindings
 }
 
 func NewHandler(in NewHandlerInput) Handler {
+	bindings := in.Bindings.ForMode(config.ModeDefault)
 	return &handler{
 		logger:   in.Logger,
 		executor: in.Executor,
-		triggers: in.Triggers,
-		prev:     make(map[uint16]time.Time),
+		runner:   in.Runner,
+		running:  make(map[string][]*execution),
+		waiting:  make(map[string][]*execution),
+		base:     in.Bindings,
+		mode:     config.ModeDefault,
+		modeKeys: make(map[uint16]heldModeKey),
+		bindings: bindings,
+		keys:     newKeyState(),
+		seqs:     newSequenceMatcher(bindings.Sequences),
+		holds:    make(map[uint16][]*holdTimer),
+		taps:     make(map[uint16]*tapState),
+		repeats:  make(map[uint16][]chan struct{}),
+		rel:      make([]int32, len(bindings.Relative)),
+		abs:      make([]absState, len(bindings.Absolute)),
+		switches: make(map[uint16]bool),
+		gestures: gesture.NewRecognizer(),
+		prev:     make(map[string]time.Time),
 	}
 }
 
 type handler struct {
+	mu       sync.Mutex
 	logger   Logger
 	executor Executor
-	triggers map[uint16]config.CommandConfig
-	prev     map[uint16]time.Time
+	runner   Runner
+	// running is the commands queued to the runner by the names of triggers,
+	// and waiting is the commands of triggers over their concurrency.
+	running map[string][]*execution
+	waiting map[string][]*execution
+	// base is the configured bindings, and bindings is the triggers active in mode.
+	base     config.Bindings
+	mode     string
+	bindings config.Bindings
+	modeKeys map[uint16]heldModeKey
+	oneShot  *oneShotMode
+	keys     *keyState
+	seqs     *sequenceMatcher
+	// deferred is the triggers of a key which started sequences,
+	// they run when the sequences are abandoned.
+	deferred *deferredTrigger
+	// holds is the timers of long presses which fire while keys are held.
+	holds map[uint16][]*holdTimer
+	// taps is the taps in progress of keys which have triggers with multiple taps.
+	taps map[uint16]*tapState
+	// repeats is the channels to stop the repeats of commands fired by held keys.
+	repeats map[uint16][]chan struct{}
+	// rel is the accumulated deltas of the relative triggers.
+	rel []int32
+	// device is the connected device, or nil while it is disconnected.
+	device evdev.Device
+	// info is the identity of the last connected device, which the commands get in the environment.
+	info evdev.DeviceInfo
+	// absInfo is the absinfo of the axes of the absolute triggers.
+	absInfo map[uint16]evdev.AbsInfo
+	abs     []absState
+	// switches is the last known states of switches, which are kept while the device is disconnected.
+	switches map[uint16]bool
+	gestures gesture.Recognizer
+	// prev is the last run of triggers by their names.
+	prev map[string]time.Time
+}
+
+// namedTrigger is the command of a trigger with its name, which identifies the trigger for the interval.
+type namedTrigger struct {
+	name string
+	cmd  config.CommandConfig
+	// ev is the event which fired the trigger.
+	ev *evdev.InputEvent
+	// delta is the delta consumed by a relative trigger.
+	delta int32
+}
+
+// newTrigger returns the trigger of cmd fired by ev.
+// The name has the mode of the trigger, so that a trigger of a mode does not share the state of the base trigger on the same key.
+func newTrigger(name string, cmd config.CommandConfig, ev *evdev.InputEvent) namedTrigger {
+	return namedTrigger{name: modeTriggerName(cmd, name), cmd: cmd, ev: ev}
+}
+
+// modeTriggerName returns name prefixed by the mode of cmd, such as "media: KEY_A".
+func modeTriggerName(cmd config.CommandConfig, name string) string {
+	if m := cmd.Mode(); m != "" {
+		return m + ": " + name
+	}
+	return name
+}
+
+type deferredTrigger struct {
+	code uint16
+	// pressed is the triggers fired by the press, which repeat if the key is still held when they run.
+	pressed  []namedTrigger
+	triggers []namedTrigger
+	timer    *time.Timer
+}
+
+type holdTimer struct {
+	timer *time.Timer
+	// stopped is true once the key is released, even if the timer already fired and waits for the lock.
+	stopped bool
+}
+
+type tapState struct {
+	count int
+	// timer runs pending when the tap window passes without the next press.
+	timer   *time.Timer
+	pending []namedTrigger
 }
 
 func (h *handler) Do(ctx context.Context, ev *evdev.InputEvent) {
-	h.logger.Debugf("Got input event: %v", ev)
+	h.logger.Debugf("Got input event in mode %s: %s", h.activeMode(), ev)
 
-	if ev.Type != evdev.EV_KEY {
-		h.logger.Debugf("Event type is not EV_KEY(%d), got %d", evdev.EV_KEY, ev.Type)
-		return
+	// gestures consist of events of several types, such as EV_ABS and EV_SYN.
+	for _, t := range h.matchGestures(ev) {
+		h.exec(ctx, t)
 	}
-	if ev.Value == 1 {
-		h.logger.Debugf("Input key is still pressing (value is %d)", ev.Value)
-		return
+	switch ev.Type {
+	case evdev.EV_KEY:
+		for _, t := range h.match(ctx, ev) {
+			h.exec(ctx, t)
+		}
+	case evdev.EV_REL:
+		for _, t := range h.matchRelative(ev) {
+			h.exec(ctx, t)
+		}
+	case evdev.EV_ABS:
+		for _, t := range h.matchAbsolute(ev) {
+			h.exec(ctx, t)
+		}
+	case evdev.EV_SW:
+		for _, t := range h.matchSwitch(ev) {
+			h.exec(ctx, t)
+		}
+	default:
+		h.logger.Debugf("Event type %s has no triggers", evdev.TypeName(ev.Type))
+	}
+}
+
+func (h *handler) activeMode() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.mode
+}
+
+// match updates the key state and the active mode by ev, and returns the triggers to run.
+func (h *handler) match(ctx context.Context, ev *evdev.InputEvent) []namedTrigger {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.switchMode(ev) {
+		return nil
 	}
+	defer h.trackOneShot(ev)
 
-	cmd, ok := h.triggers[ev.Code]
+	name := evdev.CodeName(evdev.EV_KEY, ev.Code)
+	t := ev.Timestamp()
+	switch ev.Value {
+	case 1:
+		h.stopHolds(ev.Code)
+		h.stopRepeats(ev.Code)
+		h.continueTaps(ev.Code)
+		h.keys.press(ev.Code, t)
+		h.startHolds(ctx, ev)
+		deferred, completed := h.matchSequences(ctx, ev)
+		// the triggers fired by the press repeat while the key is held.
+		held := append(completed, h.matchChords(ev)...)
+		if !h.keys.isConsumed(ev.Code) {
+			held = append(held, h.matchKey(ctx, ev, 0, false)...)
+		}
+		h.startRepeats(ctx, ev.Code, held)
+		return append(deferred, held...)
+	case 0:
+		h.stopHolds(ev.Code)
+		h.stopRepeats(ev.Code)
+		pressedAt, consumed := h.keys.release(ev.Code)
+		if consumed {
+			h.logger.Debugf("Skipped %s, it was used by a chord or a sequence", name)
+			return nil
+		}
+		return h.matchKey(ctx, ev, t.Sub(pressedAt), !pressedAt.IsZero())
+	default:
+		if h.keys.isConsumed(ev.Code) {
+			h.logger.Debugf("Skipped %s, it was used by a chord or a sequence", name)
+			return nil
+		}
+		return h.matchKey(ctx, ev, 0, false)
+	}
+}
+
+// matchKey returns the triggers of the key fired by ev.
+// held is how long the key was held until the release, and seen is false if the press was not seen.
+func (h *handler) matchKey(ctx context.Context, ev *evdev.InputEvent, held time.Duration, seen bool) []namedTrigger {
+	name := evdev.CodeName(evdev.EV_KEY, ev.Code)
+	ts, ok := h.bindings.Triggers[ev.Code]
 	if !ok {
-		h.logger.Debugf("Trigger nof found for code(%d)", ev.Code)
-		return
+		h.logger.Debugf("Trigger not found for %s", name)
+		return nil
+	}
+	taps := 1
+	multiTap := ev.Value == 0 && seen && ts.MaxTaps() > 1
+	if multiTap {
+		taps = h.countTap(ev.Code)
+	}
+	matched, long := keyTriggers(ev, ts, held, seen, taps)
+	if multiTap {
+		if !long && taps < ts.MaxTaps() {
+			h.waitTap(ctx, ev.Code, matched, ts.TapWindow())
+			return nil
+		}
+		h.endTaps(ev.Code)
+	}
+	if len(matched) == 0 {
+		h.logger.Debugf("No triggers of %s match (value is %d, held for %v)", name, ev.Value, held)
+		return nil
 	}
+	if ev.Value != 2 {
+		if deadline, ok := h.seqs.leaderDeadline(ev.Code); ok {
+			h.deferTriggers(ctx, ev.Code, matched, ev.Value == 1, deadline)
+			return nil
+		}
+	}
+	return h.readyAll(matched)
+}
 
-	prev, ok := h.prev[ev.Code]
-	if ok && time.Since(prev) < cmd.Interval {
-		h.logger.Debugf("Skipped for interval, it will takes %v until the next run", cmd.Interval-time.Since(prev))
-		return
+// keyTriggers returns the triggers in ts which are fired by ev, and whether ev is the release of a long press.
+// held is how long the key was held until the release, and seen is false if the press was not seen.
+// taps is the number of taps which ev completes.
+func keyTriggers(ev *evdev.InputEvent, ts config.KeyTriggers, held time.Duration, seen bool, taps int) ([]namedTrigger, bool) {
+	var matched []namedTrigger
+	long := -1
+	for i, t := range ts {
+		on := t.OnOrDefault()
+		switch {
+		case on.Has(config.EdgeLongPress):
+			if ev.Value != 0 || !seen || t.WhileHeld || held < ts.Threshold(i) {
+				continue
+			}
+			// only the longest of the satisfied long presses fires.
+			if long < 0 || ts.Threshold(i) > ts.Threshold(long) {
+				long = i
+			}
+		case t.TapsOrDefault() != taps:
+		case on.Has(config.EdgeShortPress):
+			if ev.Value == 0 && seen && held < ts.Threshold(i) {
+				matched = append(matched, newTrigger(t.Name(ev.Code), t.CommandConfig, ev))
+			}
+		case on.Has(edgeOf(ev.Value)):
+			matched = append(matched, newTrigger(t.Name(ev.Code), t.CommandConfig, ev))
+		}
+	}
+	if long >= 0 {
+		matched = append(matched, newTrigger(ts[long].Name(ev.Code), ts[long].CommandConfig, ev))
 	}
-	h.prev[ev.Code] = time.Now()
-	h.exec(ctx, cmd.Command)
+	return matched, long >= 0
 }
 
-func (h *handler) exec(ctx context.Context, cmd config.Command) {
-	b, err := h.executor.Do(ctx, cmd)
-	cmdStr := strings.Join(cmd, " ")
-	if err != nil {
-		h.logger.Errorf("Command %q failed: %s", cmdStr, err)
-		return
+// edgeOf returns the edge of the value of an EV_KEY event.
+func edgeOf(value int32) config.Edge {
+	switch value {
+	case 0:
+		return config.EdgeRelease
+	case 1:
+		return config.EdgePress
+	default:
+		return config.EdgeRepeat
+	}
+}
+
+// countTap counts a tap of code and returns the number of taps so far.
+func (h *handler) countTap(code uint16) int {
+	st, ok := h.taps[code]
+	if !ok {
+		st = &tapState{}
+		h.taps[code] = st
 	}
-	if len(b) > 0 {
-		h.logger.Infof("Command %q succeeded: %s", cmdStr, string(b))
+	st.count++
+	return st.count
+}
+
+// waitTap runs the triggers of the taps so far unless code is pressed again within window.
+func (h *handler) waitTap(ctx context.Context, code uint16, triggers []namedTrigger, window time.Duration) {
+	st := h.taps[code]
+	st.pending = triggers
+	var timer *time.Timer
+	timer = time.AfterFunc(window, func() {
+		h.mu.Lock()
+		if h.taps[code] != st || st.timer != timer {
+			h.mu.Unlock()
+			return
+		}
+		delete(h.taps, code)
+		triggers := h.readyAll(st.pending)
+		h.mu.Unlock()
+		for _, t := range triggers {
+			h.exec(ctx, t)
+		}
+	})
+	st.timer = timer
+}
+
+// continueTaps stops the tap window of code since the next tap is pressed,
+// and drops the triggers of the taps so far.
+func (h *handler) continueTaps(code uint16) {
+	st, ok := h.taps[code]
+	if !ok || st.timer == nil {
 		return
 	}
-	h.logger.Infof("Command %q succeeded, no output", cmdStr)
+	st.timer.Stop()
+	st.timer = nil
+	if len(st.pending) > 0 {
+		h.logger.Debugf("Dropped %d taps of %s for the next tap", st.count, evdev.CodeName(evdev.EV_KEY, code))
+	}
+	st.pending = nil
+}
+
+func (h *handler) endTaps(code uint16) {
+	if st, ok := h.taps[code]; ok && st.timer != nil {
+		st.timer.Stop()
+	}
+	delete(h.taps, code)
+}
+
+// startHolds starts the timers of the long presses of the key pressed by ev which fire while the key is held.
+func (h *handler) startHolds(ctx context.Context, ev *evdev.InputEvent) {
+	code, pressedAt := ev.Code, ev.Timestamp()
+	ts := h.bindings.Triggers[code]
+	for i, t := range ts {
+		if !t.OnOrDefault().Has(config.EdgeLongPress) || !t.WhileHeld {
+			continue
+		}
+		nt := newTrigger(t.Name(code), t.CommandConfig, ev)
+		ht := &holdTimer{}
+		ht.timer = time.AfterFunc(time.Until(pressedAt.Add(ts.Threshold(i))), func() {
+			h.mu.Lock()
+			if ht.stopped || h.keys.isConsumed(code) || !h.ready(nt) {
+				h.mu.Unlock()
+				return
+			}
+			h.startRepeats(ctx, code, []namedTrigger{nt})
+			h.mu.Unlock()
+			h.exec(ctx, nt)
+		})
+		h.holds[code] = append(h.holds[code], ht)
+	}
+}
+
+func (h *handler) stopHolds(code uint16) {
+	for _, ht := range h.holds[code] {
+		ht.stopped = true
+		ht.timer.Stop()
+	}
+	delete(h.holds, code)
+}
+
+// readyAll returns the triggers which can run.
+func (h *handler) readyAll(triggers []namedTrigger) []namedTrigger {
+	var ready []namedTrigger
+	for _, t := range triggers {
+		if h.ready(t) {
+			ready = append(ready, t)
+		}
+	}
+	return ready
+}
+
+// matchSequences advances the sequences by the press of ev.
+// It returns the deferred triggers which run since the sequences are abandoned, and the completed sequences.
+// A key which advances a sequence does not fire its own triggers,
+// and the triggers of a key which started sequences are deferred until they are abandoned.
+func (h *handler) matchSequences(ctx context.Context, ev *evdev.InputEvent) (deferred, completed []namedTrigger) {
+	seqs, advanced := h.seqs.press(ev.Code, ev.Timestamp())
+	if advanced {
+		h.keys.consume(ev.Code)
+		// the release of the first key, if it is still held, is used by the sequence too.
+		if d := h.deferred; d != nil && h.keys.isPressed(d.code) {
+			h.keys.consume(d.code)
+		}
+		h.dropDeferred()
+	} else if d := h.takeDeferred(); d != nil {
+		deferred = h.runDeferred(ctx, d)
+	}
+
+	for _, seq := range seqs {
+		h.logger.Debugf("Sequence %s is pressed", seq.Name())
+		t := newTrigger(sequenceTriggerName(seq), seq.CommandConfig, ev)
+		if h.ready(t) {
+			completed = append(completed, t)
+		}
+	}
+	return deferred, completed
+}
+
+func sequenceTriggerName(c config.SequenceConfig) string {
+	return "sequence " + c.Name()
+}
+
+// deferTriggers runs the triggers of code at deadline unless the sequences advance before it.
+// pressed is true for the triggers fired by the press, which are added to by the triggers of the release.
+// Only the triggers of one key are deferred at a time, since a new key press resolves the previous ones.
+func (h *handler) deferTriggers(ctx context.Context, code uint16, triggers []namedTrigger, pressed bool, deadline time.Time) {
+	h.logger.Debugf("Deferred %s until the sequences time out", evdev.CodeName(evdev.EV_KEY, code))
+	d := h.deferred
+	if d == nil || d.code != code {
+		d = &deferredTrigger{code: code}
+		d.timer = time.AfterFunc(time.Until(deadline), func() {
+			h.mu.Lock()
+			if h.deferred != d {
+				h.mu.Unlock()
+				return
+			}
+			h.deferred = nil
+			triggers := h.runDeferred(ctx, d)
+			h.mu.Unlock()
+			for _, t := range triggers {
+				h.exec(ctx, t)
+			}
+		})
+		h.dropDeferred()
+		h.deferred = d
+	}
+	if pressed {
+		d.pressed = append(d.pressed, triggers...)
+	} else {
+		d.triggers = append(d.triggers, triggers...)
+	}
+}
+
+// runDeferred returns the deferred triggers of d which can run,
+// and starts the repeats of the triggers of the press if the key is still held.
+func (h *handler) runDeferred(ctx context.Context, d *deferredTrigger) []namedTrigger {
+	pressed := h.readyAll(d.pressed)
+	if h.keys.isPressed(d.code) {
+		h.startRepeats(ctx, d.code, pressed)
+	}
+	return append(pressed, h.readyAll(d.triggers)...)
+}
+
+// takeDeferred stops and returns the deferred triggers if exist.
+func (h *handler) takeDeferred() *deferredTrigger {
+	d := h.deferred
+	if d == nil {
+		return nil
+	}
+	d.timer.Stop()
+	h.deferred = nil
+	return d
+}
+
+func (h *handler) dropDeferred() {
+	if d := h.takeDeferred(); d != nil {
+		h.logger.Debugf("Dropped %s, it was used by a sequence", evdev.CodeName(evdev.EV_KEY, d.code))
+	}
+}
+
+// matchChords returns the chords completed by the press of ev.
+func (h *handler) matchChords(ev *evdev.InputEvent) []namedTrigger {
+	var triggers []namedTrigger
+	for _, c := range h.bindings.Chords {
+		if !chordHas(c, ev.Code) || !h.keys.satisfies(c) {
+			continue
+		}
+		h.keys.consume(chordCodes(c)...)
+		h.logger.Debugf("Chord %s is pressed", c.Name())
+
+		t := newTrigger(chordTriggerName(c), c.CommandConfig, ev)
+		if h.ready(t) {
+			triggers = append(triggers, t)
+		}
+	}
+	return triggers
+}
+
+func chordTriggerName(c config.ChordConfig) string {
+	return "chord " + c.Name()
+}
+
+// ready reports whether the trigger t can run, and records the run if so.
+func (h *handler) ready(t namedTrigger) bool {
+	prev, ok := h.prev[t.name]
+	if ok && time.Since(prev) < t.cmd.Interval {
+		h.logger.Debugf("Skipped %s for interval, it will takes %v until the next run", t.name, t.cmd.Interval-time.Since(prev))
+		return false
+	}
+	h.prev[t.name] = time.Now()
+	return true
+}
+
+func (h *handler) Update(bindings config.Bindings) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.base = bindings
+	if !bindings.HasMode(h.mode) {
+		h.logger.Debugf("Switched mode from %s to %s, it was removed", h.mode, config.ModeDefault)
+		h.mode = config.ModeDefault
+	}
+	h.stopPending()
+	h.loadMode()
+
+	names := make(map[string]bool)
+	triggerNames(bindings, names)
+	for _, m := range bindings.ModeNames() {
+		triggerNames(bindings.ForMode(m), names)
+	}
+	for name := range h.prev {
+		if !names[name] {
+			delete(h.prev, name)
+		}
+	}
+}
+
+// triggerNames adds the names of the triggers of bindings to names.
+func triggerNames(bindings config.Bindings, names map[string]bool) {
+	for code, ts := range bindings.Triggers {
+		for _, t := range ts {
+			names[modeTriggerName(t.CommandConfig, t.Name(code))] = true
+		}
+	}
+	for _, c := range bindings.Chords {
+		names[modeTriggerName(c.CommandConfig, chordTriggerName(c))] = true
+	}
+	for _, c := range bindings.Sequences {
+		names[modeTriggerName(c.CommandConfig, sequenceTriggerName(c))] = true
+	}
+	for _, c := range bindings.Relative {
+		names[modeTriggerName(c.CommandConfig, relativeTriggerName(c))] = true
+	}
+	for _, c := range bindings.Absolute {
+		names[modeTriggerName(c.CommandConfig, absoluteTriggerName(c))] = true
+	}
+	for _, c := range bindings.Switches {
+		names[modeTriggerName(c.CommandConfig, switchTriggerName(c))] = true
+	}
+	for _, c := range bindings.Gestures {
+		names[modeTriggerName(c.CommandConfig, gestureTriggerName(c))] = true
+	}
+}
+
+func (h *handler) Connect(ctx context.Context, d evdev.Device) {
+	h.mu.Lock()
+	h.device = d
+	h.info = d.Info()
+	h.loadAbsInfo()
+	h.loadGestureRange()
+	triggers := h.loadSwitches()
+	h.mu.Unlock()
+
+	for _, t := range triggers {
+		h.exec(ctx, t)
+	}
+}
+
+func (h *handler) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stopPending()
+	h.keys.reset()
+	h.device = nil
+	h.mode = config.ModeDefault
+	h.modeKeys = make(map[uint16]heldModeKey)
+	h.oneShot = nil
+	h.loadMode()
+}
+
+// stopPending stops the timers and repeats of triggers.
+func (h *handler) stopPending() {
+	h.takeDeferred()
+	for code := range h.holds {
+		h.stopHolds(code)
+	}
+	for code := range h.taps {
+		h.endTaps(code)
+	}
+	for code := range h.repeats {
+		h.stopRepeats(code)
+	}
 }
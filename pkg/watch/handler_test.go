@@ -2,32 +2,70 @@ package watch_test
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/hareku/evdev-trigger/pkg/config"
 	"github.com/hareku/evdev-trigger/pkg/evdev"
+	"github.com/hareku/evdev-trigger/pkg/evdev/evdevmock"
 	"github.com/hareku/evdev-trigger/pkg/watch"
 	"github.com/hareku/evdev-trigger/pkg/watch/watchmock"
+	"github.com/stretchr/testify/require"
 )
 
+// syncRunner runs commands in place, so that they finish before Do returns.
+type syncRunner struct{}
+
+func (syncRunner) Go(f func()) { f() }
+func (syncRunner) Wait()       {}
+
+// jobMatcher matches the jobs of a command with the delta of the relative triggers.
+type jobMatcher struct {
+	cmd   config.Command
+	delta int32
+}
+
+func jobOf(cmd config.Command) gomock.Matcher {
+	return jobMatcher{cmd: cmd}
+}
+
+func (m jobMatcher) Matches(x interface{}) bool {
+	job, ok := x.(watch.Job)
+	return ok && gomock.Eq(m.cmd).Matches(job.Command) && job.Delta == m.delta
+}
+
+func (m jobMatcher) String() string {
+	return fmt.Sprintf("is a job of %q with delta %d", m.cmd, m.delta)
+}
+
 func Test_handler_Do(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	ctx := context.Background()
 	executor := watchmock.NewMockExecutor(ctrl)
 
-	executor.EXPECT().Do(ctx, config.Command{"echo", "Hello", "World"}).Times(1)
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "Hello", "World"})).Times(1).DoAndReturn(func(_ context.Context, job watch.Job) ([]byte, error) {
+		require.Equal(t, "KEY_9", job.Trigger)
+		require.Equal(t, int32(0), job.Event.Value)
+		return nil, nil
+	})
 
 	handler := watch.NewHandler(watch.NewHandlerInput{
 		Logger:   watch.NewLogger(io.Discard, true),
 		Executor: executor,
-		Triggers: map[uint16]config.CommandConfig{
-			10: {
-				Command: config.Command{"echo", "Hello", "World"},
-			},
-		},
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{Triggers: config.Triggers{
+			10: {{
+				CommandConfig: config.CommandConfig{
+					Command: config.Command{"echo", "Hello", "World"},
+				},
+			}},
+		}},
 	})
 
 	handler.Do(ctx, &evdev.InputEvent{
@@ -53,17 +91,20 @@ func Test_handler_Do_WithInterval(t *testing.T) {
 	ctx := context.Background()
 	executor := watchmock.NewMockExecutor(ctrl)
 
-	executor.EXPECT().Do(ctx, config.Command{"echo", "Hello", "World"}).Times(2)
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "Hello", "World"})).Times(2)
 
 	handler := watch.NewHandler(watch.NewHandlerInput{
 		Logger:   watch.NewLogger(io.Discard, true),
 		Executor: executor,
-		Triggers: map[uint16]config.CommandConfig{
-			10: {
-				Command:  config.Command{"echo", "Hello", "World"},
-				Interval: time.Millisecond * 100,
-			},
-		},
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{Triggers: config.Triggers{
+			10: {{
+				CommandConfig: config.CommandConfig{
+					Command:  config.Command{"echo", "Hello", "World"},
+					Interval: time.Millisecond * 100,
+				},
+			}},
+		}},
 	})
 
 	handler.Do(ctx, &evdev.InputEvent{
@@ -83,3 +124,1125 @@ func Test_handler_Do_WithInterval(t *testing.T) {
 		Value: 0,
 	})
 }
+
+func pressKeys(ctx context.Context, h watch.Handler, value int32, codes ...uint16) {
+	for _, code := range codes {
+		h.Do(ctx, &evdev.InputEvent{
+			Type:  evdev.EV_KEY,
+			Code:  code,
+			Value: value,
+		})
+	}
+}
+
+func Test_handler_Do_Chord(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	var (
+		leftCtrl  = uint16(29)
+		rightCtrl = uint16(97)
+		leftAlt   = uint16(56)
+		leftShift = uint16(42)
+		keyT      = uint16(20)
+	)
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"terminal"})).Times(2).DoAndReturn(func(_ context.Context, job watch.Job) ([]byte, error) {
+		// the job has the keys of the chord held at the press of T.
+		require.Len(t, job.Pressed, 3)
+		require.Contains(t, job.Pressed, keyT)
+		require.Equal(t, config.ModeDefault, job.Mode)
+		return nil, nil
+	})
+
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{
+			Triggers: config.Triggers{
+				keyT: {{CommandConfig: config.CommandConfig{Command: config.Command{"echo", "T"}}}},
+			},
+			Chords: []config.ChordConfig{{
+				Keys: config.Keys{
+					{Name: "KEY_CTRL", Codes: []uint16{leftCtrl, rightCtrl}},
+					{Name: "KEY_LEFTALT", Codes: []uint16{leftAlt}},
+					{Name: "KEY_T", Codes: []uint16{keyT}},
+				},
+				CommandConfig: config.CommandConfig{Command: config.Command{"terminal"}},
+			}},
+		},
+	})
+
+	// the chord fires on the press of T, and the release of T does not fire its single key trigger.
+	pressKeys(ctx, handler, 1, leftCtrl, leftAlt, keyT)
+	pressKeys(ctx, handler, 0, keyT, leftAlt, leftCtrl)
+
+	// right ctrl is an alias of KEY_CTRL.
+	pressKeys(ctx, handler, 1, rightCtrl, leftAlt, keyT)
+	pressKeys(ctx, handler, 0, keyT, leftAlt, rightCtrl)
+
+	// an extra shift does not match the exact chord.
+	pressKeys(ctx, handler, 1, leftShift, leftCtrl, leftAlt)
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "T"})).Times(1)
+	pressKeys(ctx, handler, 1, keyT)
+	pressKeys(ctx, handler, 0, keyT, leftAlt, leftCtrl, leftShift)
+}
+
+func Test_handler_Do_ChordSuperset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	var (
+		leftCtrl  = uint16(29)
+		leftShift = uint16(42)
+		keyT      = uint16(20)
+	)
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"terminal"})).Times(1)
+
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{
+			Chords: []config.ChordConfig{{
+				Keys: config.Keys{
+					{Name: "KEY_LEFTCTRL", Codes: []uint16{leftCtrl}},
+					{Name: "KEY_T", Codes: []uint16{keyT}},
+				},
+				Strictness:    config.StrictnessSuperset,
+				CommandConfig: config.CommandConfig{Command: config.Command{"terminal"}},
+			}},
+		},
+	})
+
+	pressKeys(ctx, handler, 1, leftShift, leftCtrl, keyT)
+	pressKeys(ctx, handler, 0, keyT, leftCtrl, leftShift)
+}
+
+func Test_handler_Do_Sequence(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	var (
+		keyA = uint16(30)
+		keyB = uint16(48)
+		keyC = uint16(46)
+	)
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "ABC"})).Times(1)
+
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{
+			Triggers: config.Triggers{
+				keyB: {{CommandConfig: config.CommandConfig{Command: config.Command{"echo", "B"}}}},
+			},
+			Sequences: []config.SequenceConfig{{
+				Keys: config.Keys{
+					{Name: "KEY_A", Codes: []uint16{keyA}},
+					{Name: "KEY_B", Codes: []uint16{keyB}},
+					{Name: "KEY_C", Codes: []uint16{keyC}},
+				},
+				Timeout:       time.Millisecond * 100,
+				CommandConfig: config.CommandConfig{Command: config.Command{"echo", "ABC"}},
+			}},
+		},
+	})
+
+	// B in the sequence does not fire its single key trigger.
+	for _, code := range []uint16{keyA, keyB, keyC} {
+		pressKeys(ctx, handler, 1, code)
+		pressKeys(ctx, handler, 0, code)
+	}
+
+	// a mismatched key resets the sequence.
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "B"})).Times(1)
+	for _, code := range []uint16{keyA, keyC, keyB, keyC} {
+		pressKeys(ctx, handler, 1, code)
+		pressKeys(ctx, handler, 0, code)
+	}
+
+	// the sequence times out.
+	pressKeys(ctx, handler, 1, keyA, keyB)
+	time.Sleep(time.Millisecond * 150)
+	pressKeys(ctx, handler, 1, keyC)
+}
+
+func Test_handler_Do_SequenceFallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	var (
+		keyA = uint16(30)
+		keyB = uint16(48)
+		keyC = uint16(46)
+	)
+	key := func(name string, code uint16) config.Key {
+		return config.Key{Name: name, Codes: []uint16{code}}
+	}
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{
+			Sequences: []config.SequenceConfig{
+				{
+					Keys:          config.Keys{key("KEY_A", keyA), key("KEY_A", keyA), key("KEY_B", keyB)},
+					CommandConfig: config.CommandConfig{Command: config.Command{"echo", "AAB"}},
+				},
+				{
+					Keys:          config.Keys{key("KEY_A", keyA), key("KEY_B", keyB), key("KEY_A", keyA), key("KEY_C", keyC)},
+					CommandConfig: config.CommandConfig{Command: config.Command{"echo", "ABAC"}},
+				},
+			},
+		},
+	})
+
+	// a mismatch falls back to the matched presses which start the sequence again.
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "AAB"})).Times(1)
+	for _, code := range []uint16{keyA, keyA, keyA, keyB} {
+		pressKeys(ctx, handler, 1, code)
+		pressKeys(ctx, handler, 0, code)
+	}
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "ABAC"})).Times(1)
+	for _, code := range []uint16{keyA, keyB, keyA, keyB, keyA, keyC} {
+		pressKeys(ctx, handler, 1, code)
+		pressKeys(ctx, handler, 0, code)
+	}
+}
+
+func Test_handler_Do_SequenceLeader(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	var (
+		leader = uint16(57)
+		keyT   = uint16(20)
+		keyX   = uint16(45)
+	)
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{
+			Triggers: config.Triggers{
+				leader: {{CommandConfig: config.CommandConfig{Command: config.Command{"echo", "leader"}}}},
+			},
+			Sequences: []config.SequenceConfig{{
+				Keys: config.Keys{
+					{Name: "KEY_SPACE", Codes: []uint16{leader}},
+					{Name: "KEY_T", Codes: []uint16{keyT}},
+				},
+				Timeout:       time.Millisecond * 100,
+				CommandConfig: config.CommandConfig{Command: config.Command{"terminal"}},
+			}},
+		},
+	})
+
+	// the leader key trigger is dropped when the sequence completes.
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"terminal"})).Times(1)
+	pressKeys(ctx, handler, 1, leader)
+	pressKeys(ctx, handler, 0, leader)
+	pressKeys(ctx, handler, 1, keyT)
+	pressKeys(ctx, handler, 0, keyT)
+
+	// the leader key trigger runs immediately when another key is pressed.
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "leader"})).Times(1)
+	pressKeys(ctx, handler, 1, leader)
+	pressKeys(ctx, handler, 0, leader)
+	pressKeys(ctx, handler, 1, keyX)
+	pressKeys(ctx, handler, 0, keyX)
+
+	// the leader key trigger runs when the sequence times out.
+	ran := make(chan struct{})
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "leader"})).Times(1).DoAndReturn(func(context.Context, watch.Job) ([]byte, error) {
+		close(ran)
+		return nil, nil
+	})
+	pressKeys(ctx, handler, 1, leader)
+	pressKeys(ctx, handler, 0, leader)
+	waitFor(t, ran)
+}
+
+func Test_handler_Do_SequenceLeaderPress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	var (
+		leader = uint16(57)
+		keyT   = uint16(20)
+		keyX   = uint16(45)
+	)
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{
+			Triggers: config.Triggers{
+				leader: {
+					{On: config.Edges{config.EdgePress}, CommandConfig: config.CommandConfig{
+						Command: config.Command{"echo", "down"},
+						Repeat:  &config.RepeatConfig{Period: time.Millisecond * 20},
+					}},
+					{CommandConfig: config.CommandConfig{Command: config.Command{"echo", "up"}}},
+				},
+			},
+			Sequences: []config.SequenceConfig{{
+				Keys: config.Keys{
+					{Name: "KEY_SPACE", Codes: []uint16{leader}},
+					{Name: "KEY_T", Codes: []uint16{keyT}},
+				},
+				Timeout:       time.Millisecond * 100,
+				CommandConfig: config.CommandConfig{Command: config.Command{"terminal"}},
+			}},
+		},
+	})
+
+	// the press and the release of the leader key are dropped when the sequence completes, even while it is held.
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"terminal"})).Times(1)
+	pressKeys(ctx, handler, 1, leader)
+	pressKeys(ctx, handler, 1, keyT)
+	pressKeys(ctx, handler, 0, keyT)
+	time.Sleep(time.Millisecond * 150)
+	pressKeys(ctx, handler, 0, leader)
+
+	// they run when another key is pressed.
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "down"})).Times(1)
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "up"})).Times(1)
+	pressKeys(ctx, handler, 1, leader)
+	pressKeys(ctx, handler, 0, leader)
+	pressKeys(ctx, handler, 1, keyX)
+	pressKeys(ctx, handler, 0, keyX)
+
+	// the press runs when the sequence times out, and repeats while the key is held.
+	repeated := make(chan struct{})
+	var n int32
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "down"})).MinTimes(2).DoAndReturn(func(context.Context, watch.Job) ([]byte, error) {
+		if atomic.AddInt32(&n, 1) == 2 {
+			close(repeated)
+		}
+		return nil, nil
+	})
+	pressKeys(ctx, handler, 1, leader)
+	waitFor(t, repeated)
+	released := make(chan struct{})
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "up"})).Times(1).DoAndReturn(func(context.Context, watch.Job) ([]byte, error) {
+		close(released)
+		return nil, nil
+	})
+	pressKeys(ctx, handler, 0, leader)
+	waitFor(t, released)
+}
+
+// pressAt sends an event of code with the kernel time at the offset.
+func pressAt(ctx context.Context, h watch.Handler, value int32, code uint16, offset time.Duration) {
+	base := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	h.Do(ctx, &evdev.InputEvent{
+		Time:  syscall.NsecToTimeval(base.Add(offset).UnixNano()),
+		Type:  evdev.EV_KEY,
+		Code:  code,
+		Value: value,
+	})
+}
+
+func Test_handler_Do_Press(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	power := uint16(116)
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{Triggers: config.Triggers{
+			power: {
+				{On: config.Edges{config.EdgeShortPress}, CommandConfig: config.CommandConfig{Command: config.Command{"suspend"}}},
+				{On: config.Edges{config.EdgeLongPress}, Threshold: time.Second, CommandConfig: config.CommandConfig{Command: config.Command{"lock"}}},
+				{On: config.Edges{config.EdgeLongPress}, Threshold: time.Second * 3, CommandConfig: config.CommandConfig{Command: config.Command{"poweroff"}}},
+			},
+		}},
+	})
+
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"suspend"})).Times(1)
+	pressAt(ctx, handler, 1, power, 0)
+	pressAt(ctx, handler, 2, power, time.Millisecond*500)
+	pressAt(ctx, handler, 0, power, time.Millisecond*900)
+
+	// only the longest of the satisfied long presses fires.
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"lock"})).Times(1)
+	pressAt(ctx, handler, 1, power, time.Second*10)
+	pressAt(ctx, handler, 0, power, time.Second*12)
+
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"poweroff"})).Times(1)
+	pressAt(ctx, handler, 1, power, time.Second*20)
+	pressAt(ctx, handler, 0, power, time.Second*23)
+
+	// a release without the press fires neither.
+	pressAt(ctx, handler, 0, power, time.Second*30)
+}
+
+func Test_handler_Do_PressWhileHeld(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	power := uint16(116)
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{Triggers: config.Triggers{
+			power: {
+				{On: config.Edges{config.EdgeShortPress}, CommandConfig: config.CommandConfig{Command: config.Command{"suspend"}}},
+				{
+					On:            config.Edges{config.EdgeLongPress},
+					Threshold:     time.Millisecond * 100,
+					WhileHeld:     true,
+					CommandConfig: config.CommandConfig{Command: config.Command{"poweroff"}},
+				},
+			},
+		}},
+	})
+
+	// the release before the threshold cancels the long press.
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"suspend"})).Times(1)
+	pressKeys(ctx, handler, 1, power)
+	pressKeys(ctx, handler, 0, power)
+	time.Sleep(time.Millisecond * 150)
+
+	ran := make(chan struct{})
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"poweroff"})).Times(1).DoAndReturn(func(context.Context, watch.Job) ([]byte, error) {
+		close(ran)
+		return nil, nil
+	})
+	pressKeys(ctx, handler, 1, power)
+	waitFor(t, ran)
+	pressKeys(ctx, handler, 0, power)
+}
+
+func Test_handler_Do_Taps(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	pedal := uint16(30)
+	window := time.Millisecond * 100
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{Triggers: config.Triggers{
+			pedal: {
+				{CommandConfig: config.CommandConfig{Command: config.Command{"echo", "1"}}},
+				{Taps: 2, TapWindow: window, CommandConfig: config.CommandConfig{Command: config.Command{"echo", "2"}}},
+				{Taps: 3, TapWindow: window, CommandConfig: config.CommandConfig{Command: config.Command{"echo", "3"}}},
+			},
+		}},
+	})
+	tap := func(n int) {
+		for i := 0; i < n; i++ {
+			pressKeys(ctx, handler, 1, pedal)
+			pressKeys(ctx, handler, 0, pedal)
+		}
+	}
+
+	// the triggers of fewer taps wait for the tap window.
+	ran := expectCommand(ctrl, executor, config.Command{"echo", "1"})
+	tap(1)
+	waitFor(t, ran)
+
+	ran = expectCommand(ctrl, executor, config.Command{"echo", "2"})
+	tap(2)
+	waitFor(t, ran)
+
+	// the most taps fire immediately.
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "3"})).Times(1)
+	tap(3)
+
+	// taps after the window start over.
+	ran = expectCommand(ctrl, executor, config.Command{"echo", "1"})
+	tap(1)
+	waitFor(t, ran)
+	ran = expectCommand(ctrl, executor, config.Command{"echo", "1"})
+	tap(1)
+	waitFor(t, ran)
+}
+
+func Test_handler_Do_Edges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	var (
+		keyA     = uint16(30)
+		volumeUp = uint16(115)
+	)
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{Triggers: config.Triggers{
+			keyA: {
+				{CommandConfig: config.CommandConfig{Command: config.Command{"echo", "release"}}},
+				{On: config.Edges{config.EdgePress}, CommandConfig: config.CommandConfig{Command: config.Command{"echo", "press"}}},
+			},
+			volumeUp: {
+				{On: config.Edges{config.EdgePress, config.EdgeRepeat}, CommandConfig: config.CommandConfig{Command: config.Command{"volume", "up"}}},
+			},
+		}},
+	})
+
+	// repeats are ignored by default.
+	gomock.InOrder(
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "press"})).Times(1),
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "release"})).Times(1),
+	)
+	pressKeys(ctx, handler, 1, keyA)
+	pressKeys(ctx, handler, 2, keyA, keyA)
+	pressKeys(ctx, handler, 0, keyA)
+
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"volume", "up"})).Times(3)
+	pressKeys(ctx, handler, 1, volumeUp)
+	pressKeys(ctx, handler, 2, volumeUp, volumeUp)
+	pressKeys(ctx, handler, 0, volumeUp)
+}
+
+func Test_handler_Do_Repeat(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	var (
+		volumeUp   = uint16(115)
+		volumeDown = uint16(114)
+	)
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{Triggers: config.Triggers{
+			volumeUp: {{
+				On: config.Edges{config.EdgePress},
+				CommandConfig: config.CommandConfig{
+					Command: config.Command{"volume", "up"},
+					Repeat:  &config.RepeatConfig{Delay: time.Millisecond * 50, Period: time.Millisecond * 10},
+				},
+			}},
+			volumeDown: {{
+				On: config.Edges{config.EdgePress},
+				CommandConfig: config.CommandConfig{
+					Command: config.Command{"volume", "down"},
+					Repeat:  &config.RepeatConfig{Period: time.Millisecond * 10, Max: 2},
+				},
+			}},
+		}},
+	})
+
+	// the command runs on the press and repeats while the key is held.
+	runs := make(chan struct{}, 100)
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"volume", "up"})).MinTimes(4).DoAndReturn(func(context.Context, watch.Job) ([]byte, error) {
+		runs <- struct{}{}
+		return nil, nil
+	})
+	pressKeys(ctx, handler, 1, volumeUp)
+	for i := 0; i < 4; i++ {
+		waitFor(t, runs)
+	}
+	pressKeys(ctx, handler, 0, volumeUp)
+	time.Sleep(time.Millisecond * 20)
+	n := len(runs)
+	time.Sleep(time.Millisecond * 50)
+	require.Equal(t, n, len(runs), "repeats stop on the release")
+
+	// repeats stop at max.
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"volume", "down"})).Times(3)
+	pressKeys(ctx, handler, 1, volumeDown)
+	time.Sleep(time.Millisecond * 100)
+	pressKeys(ctx, handler, 0, volumeDown)
+}
+
+func Test_handler_Reset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	var (
+		leftCtrl = uint16(29)
+		keyT     = uint16(20)
+	)
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{
+			Triggers: config.Triggers{
+				keyT: {{
+					On: config.Edges{config.EdgePress},
+					CommandConfig: config.CommandConfig{
+						Command: config.Command{"echo", "T"},
+						Repeat:  &config.RepeatConfig{Period: time.Millisecond * 10},
+					},
+				}},
+			},
+			Chords: []config.ChordConfig{{
+				Keys: config.Keys{
+					{Name: "KEY_LEFTCTRL", Codes: []uint16{leftCtrl}},
+					{Name: "KEY_T", Codes: []uint16{keyT}},
+				},
+				CommandConfig: config.CommandConfig{Command: config.Command{"terminal"}},
+			}},
+		},
+	})
+
+	// the repeats stop when the device is disconnected.
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "T"})).Times(1)
+	pressKeys(ctx, handler, 1, keyT)
+	handler.Reset()
+	time.Sleep(time.Millisecond * 50)
+
+	// the keys held before the disconnection are forgotten.
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "T"})).Times(1)
+	pressKeys(ctx, handler, 1, leftCtrl)
+	handler.Reset()
+	pressKeys(ctx, handler, 1, keyT)
+	handler.Reset()
+}
+
+func Test_handler_Do_Relative(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	var (
+		relWheel = uint16(0x08)
+		relDial  = uint16(0x07)
+	)
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{Relative: []config.RelativeConfig{
+			{
+				Axis:          config.RelCode(relWheel),
+				Direction:     config.DirectionPositive,
+				CommandConfig: config.CommandConfig{Command: config.Command{"scroll", "up"}},
+			},
+			{
+				Axis:          config.RelCode(relDial),
+				Threshold:     3,
+				CommandConfig: config.CommandConfig{Command: config.Command{"volume"}},
+			},
+		}},
+	})
+	move := func(code uint16, values ...int32) {
+		for _, v := range values {
+			handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_REL, Code: code, Value: v})
+		}
+	}
+
+	// only the positive direction fires.
+	executor.EXPECT().Do(gomock.Any(), jobMatcher{cmd: config.Command{"scroll", "up"}, delta: 1}).Times(2)
+	executor.EXPECT().Do(gomock.Any(), jobMatcher{cmd: config.Command{"scroll", "up"}, delta: 2}).Times(1)
+	move(relWheel, 1, -1, 1, 2)
+
+	// the deltas are accumulated up to the threshold in both directions, and reset when the direction changes.
+	gomock.InOrder(
+		executor.EXPECT().Do(gomock.Any(), jobMatcher{cmd: config.Command{"volume"}, delta: 3}).Times(1),
+		executor.EXPECT().Do(gomock.Any(), jobMatcher{cmd: config.Command{"volume"}, delta: -6}).Times(1),
+	)
+	move(relDial, 1, 1, 2, -1, -7)
+}
+
+func Test_handler_Do_Absolute(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	absZ := uint16(0x02)
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{Absolute: []config.AbsoluteConfig{
+			{
+				Axis:          config.AbsCode(absZ),
+				On:            config.CrossingRising,
+				Threshold:     0.5,
+				Hysteresis:    0.1,
+				Normalize:     true,
+				CommandConfig: config.CommandConfig{Command: config.Command{"echo", "down"}},
+			},
+			{
+				Axis:          config.AbsCode(absZ),
+				On:            config.CrossingFalling,
+				Threshold:     0.4,
+				Hysteresis:    0.1,
+				Normalize:     true,
+				CommandConfig: config.CommandConfig{Command: config.Command{"echo", "up"}},
+			},
+		}},
+	})
+
+	// the current value of the connected device does not fire.
+	device := evdevmock.NewMockDevice(ctrl)
+	device.EXPECT().Info().AnyTimes()
+	device.EXPECT().AbsInfo(absZ).Times(1).Return(evdev.AbsInfo{Value: 900, Minimum: 0, Maximum: 1000}, nil)
+	handler.Connect(ctx, device)
+
+	move := func(values ...int32) {
+		for _, v := range values {
+			handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_ABS, Code: absZ, Value: v})
+		}
+	}
+	gomock.InOrder(
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "up"})).Times(1),
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "down"})).Times(1),
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "up"})).Times(1),
+	)
+	// noise around the thresholds does not fire again.
+	move(800, 300, 450, 350, 450, 600, 450, 550, 450, 100)
+}
+
+func Test_handler_Do_AbsoluteRange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	absX := uint16(0x00)
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{Absolute: []config.AbsoluteConfig{{
+			Axis:          config.AbsCode(absX),
+			On:            config.CrossingRange,
+			Min:           100,
+			Max:           200,
+			CommandConfig: config.CommandConfig{Command: config.Command{"echo", "middle"}},
+		}}},
+	})
+
+	move := func(values ...int32) {
+		for _, v := range values {
+			handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_ABS, Code: absX, Value: v})
+		}
+	}
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"echo", "middle"})).Times(2)
+	// the first value only arms the trigger.
+	move(150, 50, 150, 180, 250, 120)
+}
+
+func Test_handler_Do_Switch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	var (
+		swLid       = uint16(0x00)
+		swHeadphone = uint16(0x02)
+	)
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{Switches: []config.SwitchConfig{
+			{Switch: config.SwCode(swLid), State: config.SwitchOn, CommandConfig: config.CommandConfig{Command: config.Command{"lock"}}},
+			{Switch: config.SwCode(swLid), State: config.SwitchOff, CommandConfig: config.CommandConfig{Command: config.Command{"unlock"}}},
+			{Switch: config.SwCode(swHeadphone), State: config.SwitchOn, Initial: true, CommandConfig: config.CommandConfig{Command: config.Command{"headphone"}}},
+		}},
+	})
+
+	// at the first connection, only the initial triggers fire.
+	device1 := evdevmock.NewMockDevice(ctrl)
+	device1.EXPECT().Info().AnyTimes().Return(evdev.DeviceInfo{Path: "/dev/input/event0", Name: "Lid Switch"})
+	device1.EXPECT().SwitchState(swLid).Return(false, nil)
+	device1.EXPECT().SwitchState(swHeadphone).Return(true, nil)
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"headphone"})).Times(1).DoAndReturn(func(_ context.Context, job watch.Job) ([]byte, error) {
+		// the state on connection is passed as an event of the switch.
+		require.Equal(t, "Lid Switch", job.Device.Name)
+		require.Equal(t, evdev.EV_SW, job.Event.Type)
+		require.Equal(t, swHeadphone, job.Event.Code)
+		require.Equal(t, int32(1), job.Event.Value)
+		return nil, nil
+	})
+	handler.Connect(ctx, device1)
+
+	gomock.InOrder(
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"lock"})).Times(1),
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"unlock"})).Times(1),
+	)
+	handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_SW, Code: swLid, Value: 1})
+	handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_SW, Code: swLid, Value: 0})
+	handler.Reset()
+
+	// the lid was closed while disconnected.
+	device2 := evdevmock.NewMockDevice(ctrl)
+	device2.EXPECT().Info().AnyTimes()
+	device2.EXPECT().SwitchState(swLid).Return(true, nil)
+	device2.EXPECT().SwitchState(swHeadphone).Return(true, nil)
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"lock"})).Times(1)
+	handler.Connect(ctx, device2)
+}
+
+func Test_handler_Do_Gesture(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{Gestures: []config.GestureConfig{
+			{Gesture: config.GestureSwipe, Fingers: 2, Direction: config.GestureLeft, CommandConfig: config.CommandConfig{Command: config.Command{"back"}}},
+			{Gesture: config.GestureTap, Fingers: 2, CommandConfig: config.CommandConfig{Command: config.Command{"menu"}}},
+		}},
+	})
+
+	device := evdevmock.NewMockDevice(ctrl)
+	device.EXPECT().Info().AnyTimes()
+	device.EXPECT().AbsInfo(evdev.ABS_MT_POSITION_X).Return(evdev.AbsInfo{Maximum: 1000}, nil)
+	device.EXPECT().AbsInfo(evdev.ABS_MT_POSITION_Y).Return(evdev.AbsInfo{Maximum: 500}, nil)
+	handler.Connect(ctx, device)
+
+	// frame sends pairs of codes and values, followed by SYN_REPORT.
+	base := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	frame := func(offset time.Duration, pairs ...int32) {
+		tv := syscall.NsecToTimeval(base.Add(offset).UnixNano())
+		for i := 0; i+1 < len(pairs); i += 2 {
+			handler.Do(ctx, &evdev.InputEvent{Time: tv, Type: evdev.EV_ABS, Code: uint16(pairs[i]), Value: pairs[i+1]})
+		}
+		handler.Do(ctx, &evdev.InputEvent{Time: tv, Type: evdev.EV_SYN, Code: evdev.SYN_REPORT})
+	}
+	down := func(offset time.Duration, x0, x1 int32) {
+		frame(offset,
+			int32(evdev.ABS_MT_SLOT), 0, int32(evdev.ABS_MT_TRACKING_ID), 1, int32(evdev.ABS_MT_POSITION_X), x0, int32(evdev.ABS_MT_POSITION_Y), 250,
+			int32(evdev.ABS_MT_SLOT), 1, int32(evdev.ABS_MT_TRACKING_ID), 2, int32(evdev.ABS_MT_POSITION_X), x1, int32(evdev.ABS_MT_POSITION_Y), 250,
+		)
+	}
+	move := func(offset time.Duration, x0, x1 int32) {
+		frame(offset, int32(evdev.ABS_MT_SLOT), 0, int32(evdev.ABS_MT_POSITION_X), x0, int32(evdev.ABS_MT_SLOT), 1, int32(evdev.ABS_MT_POSITION_X), x1)
+	}
+	up := func(offset time.Duration) {
+		frame(offset, int32(evdev.ABS_MT_SLOT), 0, int32(evdev.ABS_MT_TRACKING_ID), -1, int32(evdev.ABS_MT_SLOT), 1, int32(evdev.ABS_MT_TRACKING_ID), -1)
+	}
+
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"back"})).Times(1)
+	down(0, 600, 700)
+	move(50*time.Millisecond, 300, 400)
+	up(100 * time.Millisecond)
+
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"menu"})).Times(1)
+	down(time.Second, 600, 700)
+	up(time.Second + 100*time.Millisecond)
+
+	// a slow tap is not a tap.
+	down(2*time.Second, 600, 700)
+	up(3 * time.Second)
+}
+
+func Test_handler_Do_Modes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	var (
+		keyA   = uint16(30)
+		keyB   = uint16(48)
+		keyF13 = uint16(183)
+		keyF14 = uint16(184)
+		keyF15 = uint16(185)
+	)
+	command := func(args ...string) config.KeyTriggers {
+		return config.KeyTriggers{{CommandConfig: config.CommandConfig{Command: args}}}
+	}
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{
+			Triggers: config.Triggers{keyA: command("a"), keyB: command("b")},
+			ModeKeys: []config.ModeKeyConfig{
+				{Key: config.KeyCode(keyF13), Mode: "media", Action: config.ModeMomentary},
+				{Key: config.KeyCode(keyF14), Mode: "media", Action: config.ModeToggle},
+				{Key: config.KeyCode(keyF15), Mode: "media", Action: config.ModeOneShot},
+			},
+			Modes: map[string]config.ModeConfig{
+				"media": {Bindings: config.Bindings{Triggers: config.Triggers{keyA: command("play")}}},
+			},
+		},
+	})
+	tap := func(code uint16) {
+		handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_KEY, Code: code, Value: 1})
+		handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_KEY, Code: code, Value: 0})
+	}
+
+	// momentary, while F13 is held.
+	gomock.InOrder(
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"play"})).Times(1),
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"b"})).Times(1),
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"a"})).Times(1),
+	)
+	handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_KEY, Code: keyF13, Value: 1})
+	tap(keyA)
+	tap(keyB)
+	handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_KEY, Code: keyF13, Value: 0})
+	tap(keyA)
+
+	// toggle, until F14 is pressed again.
+	gomock.InOrder(
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"play"})).Times(2),
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"a"})).Times(1),
+	)
+	tap(keyF14)
+	tap(keyA)
+	tap(keyA)
+	tap(keyF14)
+	tap(keyA)
+
+	// one-shot, for the next key.
+	gomock.InOrder(
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"play"})).Times(1),
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"a"})).Times(1),
+	)
+	tap(keyF15)
+	tap(keyA)
+	tap(keyA)
+
+	// Reset returns to the default mode.
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"a"})).Times(1)
+	tap(keyF14)
+	handler.Reset()
+	tap(keyA)
+}
+
+func Test_handler_Do_ModeTriggerNames(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	var (
+		keyA   = uint16(30)
+		keyF14 = uint16(184)
+	)
+	command := func(args ...string) config.KeyTriggers {
+		return config.KeyTriggers{{CommandConfig: config.CommandConfig{Command: args, Interval: time.Hour}}}
+	}
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{
+			Triggers: config.Triggers{keyA: command("a")},
+			ModeKeys: []config.ModeKeyConfig{{Key: config.KeyCode(keyF14), Mode: "media", Action: config.ModeToggle}},
+			Modes: map[string]config.ModeConfig{
+				"media": {Bindings: config.Bindings{Triggers: config.Triggers{keyA: command("play")}}},
+			},
+		},
+	})
+	tap := func(code uint16) {
+		handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_KEY, Code: code, Value: 1})
+		handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_KEY, Code: code, Value: 0})
+	}
+
+	// the trigger of the mode has its own name, so the interval of the base trigger does not skip it.
+	trigger := func(name string) func(context.Context, watch.Job) ([]byte, error) {
+		return func(_ context.Context, job watch.Job) ([]byte, error) {
+			require.Equal(t, name, job.Trigger)
+			return nil, nil
+		}
+	}
+	gomock.InOrder(
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"a"})).Times(1).DoAndReturn(trigger("KEY_A")),
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"play"})).Times(1).DoAndReturn(trigger("media: KEY_A")),
+	)
+	tap(keyA)
+	tap(keyF14)
+	tap(keyA)
+	tap(keyA)
+	tap(keyF14)
+	tap(keyA)
+}
+
+func Test_handler_Do_Concurrency(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+	runner := watch.NewRunner(0)
+
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   runner,
+		Bindings: config.Bindings{Triggers: config.Triggers{
+			10: {{CommandConfig: config.CommandConfig{Command: config.Command{"slow"}, Concurrency: 1}}},
+			11: {{CommandConfig: config.CommandConfig{Command: config.Command{"fast"}}}},
+		}},
+	})
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	running := 0
+	var mu sync.Mutex
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"slow"})).Times(2).DoAndReturn(func(context.Context, watch.Job) ([]byte, error) {
+		mu.Lock()
+		running++
+		require.Equal(t, 1, running)
+		mu.Unlock()
+		started <- struct{}{}
+		<-release
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil, nil
+	})
+	fast := make(chan struct{})
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"fast"})).Times(1).DoAndReturn(func(context.Context, watch.Job) ([]byte, error) {
+		close(fast)
+		return nil, nil
+	})
+
+	// Do returns while the slow command runs, and the second run waits for the first.
+	handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_KEY, Code: 10, Value: 0})
+	handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_KEY, Code: 10, Value: 0})
+	handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_KEY, Code: 11, Value: 0})
+	waitFor(t, started)
+	waitFor(t, fast)
+	close(release)
+	waitFor(t, started)
+	runner.Wait()
+}
+
+func Test_handler_Do_Overlap(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+	runner := watch.NewRunner(0)
+
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   runner,
+		Bindings: config.Bindings{Triggers: config.Triggers{
+			10: {{CommandConfig: config.CommandConfig{Command: config.Command{"drop"}, Overlap: config.OverlapDrop}}},
+			11: {{CommandConfig: config.CommandConfig{Command: config.Command{"restart"}, Overlap: config.OverlapRestart}}},
+		}},
+	})
+	release := func(code uint16) {
+		handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_KEY, Code: code, Value: 0})
+	}
+
+	// the second run is dropped while the first runs.
+	started := make(chan struct{})
+	done := make(chan struct{})
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"drop"})).Times(1).DoAndReturn(func(context.Context, watch.Job) ([]byte, error) {
+		close(started)
+		<-done
+		return nil, nil
+	})
+	release(10)
+	waitFor(t, started)
+	release(10)
+	close(done)
+	runner.Wait()
+
+	// the second run stops the first.
+	started = make(chan struct{})
+	stopped := make(chan struct{})
+	gomock.InOrder(
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"restart"})).Times(1).DoAndReturn(func(ctx context.Context, _ watch.Job) ([]byte, error) {
+			close(started)
+			<-ctx.Done()
+			close(stopped)
+			return nil, ctx.Err()
+		}),
+		executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"restart"})).Times(1).DoAndReturn(func(context.Context, watch.Job) ([]byte, error) {
+			<-stopped
+			return nil, nil
+		}),
+	)
+	release(11)
+	waitFor(t, started)
+	release(11)
+	runner.Wait()
+
+	// the runs being stopped do not count, so quick presses stop all but the last run.
+	var mu sync.Mutex
+	var ctxs []context.Context
+	stop := make(chan struct{})
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"restart"})).Times(3).DoAndReturn(func(ctx context.Context, _ watch.Job) ([]byte, error) {
+		mu.Lock()
+		ctxs = append(ctxs, ctx)
+		mu.Unlock()
+		// the commands take the grace period to stop.
+		<-stop
+		return nil, ctx.Err()
+	})
+	for i := 0; i < 3; i++ {
+		release(11)
+	}
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(ctxs) == 3
+	}, time.Second, time.Millisecond)
+	mu.Lock()
+	cancelled := 0
+	for _, c := range ctxs {
+		if c.Err() != nil {
+			cancelled++
+		}
+	}
+	mu.Unlock()
+	require.Equal(t, 2, cancelled)
+	close(stop)
+	runner.Wait()
+
+	// a trigger which restarts after a reload stops the run of the previous one.
+	handler.Update(config.Bindings{Triggers: config.Triggers{
+		12: {{CommandConfig: config.CommandConfig{Command: config.Command{"queue"}, Concurrency: 1}}},
+	}})
+	started = make(chan struct{})
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"queue"})).Times(1).DoAndReturn(func(ctx context.Context, _ watch.Job) ([]byte, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	release(12)
+	waitFor(t, started)
+	handler.Update(config.Bindings{Triggers: config.Triggers{
+		12: {{CommandConfig: config.CommandConfig{Command: config.Command{"restart"}, Overlap: config.OverlapRestart}}},
+	}})
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"restart"})).Times(1)
+	release(12)
+	runner.Wait()
+}
+
+func Test_handler_Do_Timeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	executor := watchmock.NewMockExecutor(ctrl)
+
+	handler := watch.NewHandler(watch.NewHandlerInput{
+		Logger:   watch.NewLogger(io.Discard, true),
+		Executor: executor,
+		Runner:   syncRunner{},
+		Bindings: config.Bindings{Triggers: config.Triggers{
+			10: {{CommandConfig: config.CommandConfig{Command: config.Command{"hang"}, Timeout: 50 * time.Millisecond}}},
+		}},
+	})
+
+	executor.EXPECT().Do(gomock.Any(), jobOf(config.Command{"hang"})).Times(1).DoAndReturn(func(ctx context.Context, _ watch.Job) ([]byte, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	handler.Do(ctx, &evdev.InputEvent{Type: evdev.EV_KEY, Code: 10, Value: 0})
+}
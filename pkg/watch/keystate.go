@@ -0,0 +1,69 @@
+package watch
+
+import (
+	"sort"
+	"time"
+)
+
+// keyState tracks the pressed keys of a device.
+type keyState struct {
+	// pressed is the time when each pressed key was pressed.
+	pressed map[uint16]time.Time
+	// consumed keys were used by a chord or a sequence,
+	// so their release does not fire single key triggers.
+	consumed map[uint16]bool
+}
+
+func newKeyState() *keyState {
+	return &keyState{
+		pressed:  make(map[uint16]time.Time),
+		consumed: make(map[uint16]bool),
+	}
+}
+
+func (s *keyState) press(code uint16, t time.Time) {
+	s.pressed[code] = t
+}
+
+// release marks code released and reports whether it was consumed.
+// It also returns the time when code was pressed, or the zero time if it was not pressed.
+func (s *keyState) release(code uint16) (time.Time, bool) {
+	at := s.pressed[code]
+	consumed := s.consumed[code]
+	delete(s.pressed, code)
+	delete(s.consumed, code)
+	return at, consumed
+}
+
+func (s *keyState) isPressed(code uint16) bool {
+	_, ok := s.pressed[code]
+	return ok
+}
+
+// pressedCodes returns the codes of the pressed keys in ascending order.
+func (s *keyState) pressedCodes() []uint16 {
+	codes := make([]uint16, 0, len(s.pressed))
+	for code := range s.pressed {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	return codes
+}
+
+func (s *keyState) isConsumed(code uint16) bool {
+	return s.consumed[code]
+}
+
+// consume marks the pressed keys among codes consumed.
+func (s *keyState) consume(codes ...uint16) {
+	for _, code := range codes {
+		if s.isPressed(code) {
+			s.consumed[code] = true
+		}
+	}
+}
+
+func (s *keyState) reset() {
+	s.pressed = make(map[uint16]time.Time)
+	s.consumed = make(map[uint16]bool)
+}
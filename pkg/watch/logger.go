@@ -37,3 +37,28 @@ func (s *stdLogger) Debugf(format string, args ...interface{}) {
 func (s *stdLogger) Errorf(format string, args ...interface{}) {
 	s.l.Printf("[ERROR]: %s", fmt.Sprintf(format, args...))
 }
+
+// WithPrefix returns a logger which prepends prefix to every message.
+func WithPrefix(l Logger, prefix string) Logger {
+	return &prefixLogger{
+		l:      l,
+		prefix: prefix,
+	}
+}
+
+type prefixLogger struct {
+	l      Logger
+	prefix string
+}
+
+func (p *prefixLogger) Infof(format string, args ...interface{}) {
+	p.l.Infof("%s: %s", p.prefix, fmt.Sprintf(format, args...))
+}
+
+func (p *prefixLogger) Debugf(format string, args ...interface{}) {
+	p.l.Debugf("%s: %s", p.prefix, fmt.Sprintf(format, args...))
+}
+
+func (p *prefixLogger) Errorf(format string, args ...interface{}) {
+	p.l.Errorf("%s: %s", p.prefix, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,108 @@
+package watch
+
+import (
+	"github.com/hareku/evdev-trigger/pkg/config"
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+)
+
+// oneShotMode is a mode switched by a one-shot mode key, which lasts until the next key is released.
+type oneShotMode struct {
+	// prev is the mode to return to.
+	prev string
+	// pressed is true once the next key is pressed, and key is the key.
+	pressed bool
+	key     uint16
+}
+
+// heldModeKey is a mode key being held, whose release and repeats are consumed in any mode.
+type heldModeKey struct {
+	momentary bool
+	// prev is the mode to return to by the release of a momentary mode key.
+	prev string
+}
+
+// switchMode switches the active mode if ev is of a mode key, and reports whether it is.
+// Mode keys do not fire triggers.
+func (h *handler) switchMode(ev *evdev.InputEvent) bool {
+	if held, ok := h.modeKeys[ev.Code]; ok {
+		if ev.Value == 0 {
+			delete(h.modeKeys, ev.Code)
+			if held.momentary {
+				h.setMode(held.prev)
+			}
+		}
+		return true
+	}
+
+	k, ok := h.modeKey(ev.Code)
+	if !ok {
+		return false
+	}
+	if ev.Value != 1 {
+		return true
+	}
+	h.modeKeys[ev.Code] = heldModeKey{momentary: k.ActionOrDefault() == config.ModeMomentary, prev: h.mode}
+	switch k.ActionOrDefault() {
+	case config.ModeMomentary:
+		h.setMode(k.Mode)
+	case config.ModeOneShot:
+		h.oneShot = &oneShotMode{prev: h.mode}
+		h.setMode(k.Mode)
+	default:
+		if h.mode == k.Mode {
+			h.setMode(config.ModeDefault)
+		} else {
+			h.setMode(k.Mode)
+		}
+	}
+	return true
+}
+
+func (h *handler) modeKey(code uint16) (config.ModeKeyConfig, bool) {
+	for _, k := range h.bindings.ModeKeys {
+		if uint16(k.Key) == code {
+			return k, true
+		}
+	}
+	return config.ModeKeyConfig{}, false
+}
+
+// trackOneShot returns to the previous mode when the key pressed in a one-shot mode is released.
+func (h *handler) trackOneShot(ev *evdev.InputEvent) {
+	s := h.oneShot
+	if s == nil {
+		return
+	}
+	switch {
+	case ev.Value == 1 && !s.pressed:
+		s.pressed = true
+		s.key = ev.Code
+	case ev.Value == 0 && s.pressed && s.key == ev.Code:
+		h.oneShot = nil
+		h.setMode(s.prev)
+	}
+}
+
+// setMode switches the active mode to mode.
+// The held keys and the pending triggers are kept.
+func (h *handler) setMode(mode string) {
+	if !h.base.HasMode(mode) {
+		mode = config.ModeDefault
+	}
+	if mode == h.mode {
+		return
+	}
+	h.logger.Debugf("Switched mode from %s to %s", h.mode, mode)
+	h.mode = mode
+	h.loadMode()
+}
+
+// loadMode replaces the bindings by the triggers active in the mode.
+func (h *handler) loadMode() {
+	h.bindings = h.base.ForMode(h.mode)
+	h.seqs = newSequenceMatcher(h.bindings.Sequences)
+	h.rel = make([]int32, len(h.bindings.Relative))
+	h.loadAbsInfo()
+	h.gestures.Reset()
+	h.loadGestureRange()
+}
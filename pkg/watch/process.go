@@ -0,0 +1,105 @@
+package watch
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/hareku/evdev-trigger/pkg/config"
+)
+
+// credential returns the credential of the user and the group of p, or nil if neither is set.
+// env is the variables of the user, such as HOME, which replace the ones of evdev-trigger.
+func credential(p config.ProcessConfig) (cred *syscall.Credential, env []string, err error) {
+	if p.User == "" && p.Group == "" {
+		return nil, nil, nil
+	}
+	cred = &syscall.Credential{Uid: uint32(os.Getuid()), Gid: uint32(os.Getgid())}
+	if p.User != "" {
+		u, err := config.LookupUser(p.User)
+		if err != nil {
+			return nil, nil, fmt.Errorf("looking up user %q failed: %w", p.User, err)
+		}
+		if cred.Uid, err = parseID(u.Uid); err != nil {
+			return nil, nil, err
+		}
+		if cred.Gid, err = parseID(u.Gid); err != nil {
+			return nil, nil, err
+		}
+		gids, err := u.GroupIds()
+		if err != nil {
+			return nil, nil, fmt.Errorf("looking up groups of user %q failed: %w", p.User, err)
+		}
+		for _, gid := range gids {
+			id, err := parseID(gid)
+			if err != nil {
+				return nil, nil, err
+			}
+			cred.Groups = append(cred.Groups, id)
+		}
+		env = []string{"HOME=" + u.HomeDir, "USER=" + u.Username, "LOGNAME=" + u.Username}
+	}
+	if p.Group != "" {
+		g, err := config.LookupGroup(p.Group)
+		if err != nil {
+			return nil, nil, fmt.Errorf("looking up group %q failed: %w", p.Group, err)
+		}
+		if cred.Gid, err = parseID(g.Gid); err != nil {
+			return nil, nil, err
+		}
+	}
+	return cred, env, nil
+}
+
+// session returns p with the user of the session, and the environment of the session if p runs commands in it.
+func (e *executor) session(p config.ProcessConfig) (config.ProcessConfig, []string, error) {
+	if p.Session != config.SessionActiveUser {
+		return p, nil, nil
+	}
+	s, err := e.sessions.ActiveUser()
+	if err != nil {
+		return p, nil, fmt.Errorf("finding the active session failed: %w", err)
+	}
+	p.User = s.UID
+	return p, s.Env, nil
+}
+
+func parseID(s string) (uint32, error) {
+	id, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("parsing id %q failed: %w", s, err)
+	}
+	return uint32(id), nil
+}
+
+// environ returns the environment of the command of job.
+// The later variables win, in the order of the inherited ones, userEnv, the ones of the trigger and the configured ones.
+func environ(job Job, userEnv []string) []string {
+	var env []string
+	if job.Process.Env.InheritOrDefault() {
+		unset := make(map[string]bool, len(job.Process.Env.Unset))
+		for _, k := range job.Process.Env.Unset {
+			unset[k] = true
+		}
+		for _, kv := range os.Environ() {
+			if !unset[strings.SplitN(kv, "=", 2)[0]] {
+				env = append(env, kv)
+			}
+		}
+	}
+	env = append(env, userEnv...)
+	env = append(env, job.Environ()...)
+
+	keys := make([]string, 0, len(job.Process.Env.Set))
+	for k := range job.Process.Env.Set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		env = append(env, k+"="+job.Process.Env.Set[k])
+	}
+	return env
+}
@@ -0,0 +1,46 @@
+package watch
+
+import (
+	"github.com/hareku/evdev-trigger/pkg/config"
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+)
+
+// matchRelative accumulates the value of ev and returns the triggers which reach their thresholds.
+func (h *handler) matchRelative(ev *evdev.InputEvent) []namedTrigger {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var triggers []namedTrigger
+	for i, c := range h.bindings.Relative {
+		if uint16(c.Axis) != ev.Code || ev.Value == 0 {
+			continue
+		}
+		acc := h.rel[i]
+		if acc != 0 && (acc > 0) != (ev.Value > 0) {
+			acc = 0
+		}
+		if (c.Direction == config.DirectionPositive && ev.Value < 0) || (c.Direction == config.DirectionNegative && ev.Value > 0) {
+			h.rel[i] = 0
+			continue
+		}
+		acc += ev.Value
+
+		th := c.ThresholdOrDefault()
+		delta := acc / th * th
+		h.rel[i] = acc - delta
+		if delta == 0 {
+			h.logger.Debugf("Relative %s accumulated %d of %d", c.Name(), acc, th)
+			continue
+		}
+		t := newTrigger(relativeTriggerName(c), c.CommandConfig, ev)
+		t.delta = delta
+		if h.ready(t) {
+			triggers = append(triggers, t)
+		}
+	}
+	return triggers
+}
+
+func relativeTriggerName(c config.RelativeConfig) string {
+	return "relative " + c.Name()
+}
@@ -0,0 +1,49 @@
+package watch
+
+import (
+	"context"
+	"time"
+)
+
+// startRepeats runs the triggers with the repeat mode again while code is held.
+// The repeats stop on the release of code, on Reset and on the cancellation of ctx.
+func (h *handler) startRepeats(ctx context.Context, code uint16, triggers []namedTrigger) {
+	for _, t := range triggers {
+		if t.cmd.Repeat == nil {
+			continue
+		}
+		stop := make(chan struct{})
+		h.repeats[code] = append(h.repeats[code], stop)
+		go h.repeat(ctx, stop, t)
+	}
+}
+
+func (h *handler) repeat(ctx context.Context, stop <-chan struct{}, t namedTrigger) {
+	r := t.cmd.Repeat
+	timer := time.NewTimer(r.DelayOrDefault())
+	defer timer.Stop()
+	for n := 0; r.Max == 0 || n < r.Max; n++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-timer.C:
+		}
+		// the key may be released while the timer fires.
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		h.exec(ctx, t)
+		timer.Reset(r.Period)
+	}
+}
+
+func (h *handler) stopRepeats(code uint16) {
+	for _, stop := range h.repeats[code] {
+		close(stop)
+	}
+	delete(h.repeats, code)
+}
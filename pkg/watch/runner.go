@@ -0,0 +1,66 @@
+package watch
+
+import "sync"
+
+// DefaultConcurrency is the default number of commands which run at once.
+const DefaultConcurrency = 8
+
+// Runner runs commands in a bounded pool of workers, so that slow commands do not block reading events.
+type Runner interface {
+	// Go queues f to run in a worker, and returns without waiting for it.
+	Go(f func())
+	// Wait waits until all queued functions finish.
+	Wait()
+}
+
+type runner struct {
+	mu      sync.Mutex
+	limit   int
+	workers int
+	queue   []func()
+	wg      sync.WaitGroup
+}
+
+// NewRunner returns a Runner which runs at most limit functions at once.
+// If limit is not positive, DefaultConcurrency is used.
+func NewRunner(limit int) Runner {
+	if limit <= 0 {
+		limit = DefaultConcurrency
+	}
+	return &runner{limit: limit}
+}
+
+func (r *runner) Go(f func()) {
+	r.wg.Add(1)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.queue = append(r.queue, f)
+	// workers exit when the queue is empty, so they are started on demand.
+	if r.workers < r.limit {
+		r.workers++
+		go r.work()
+	}
+}
+
+func (r *runner) work() {
+	for {
+		r.mu.Lock()
+		if len(r.queue) == 0 {
+			r.workers--
+			r.mu.Unlock()
+			return
+		}
+		f := r.queue[0]
+		r.queue[0] = nil
+		r.queue = r.queue[1:]
+		r.mu.Unlock()
+
+		f()
+		r.wg.Done()
+	}
+}
+
+func (r *runner) Wait() {
+	r.wg.Wait()
+}
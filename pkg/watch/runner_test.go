@@ -0,0 +1,47 @@
+package watch_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/hareku/evdev-trigger/pkg/watch"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_runner_Go(t *testing.T) {
+	r := watch.NewRunner(2)
+
+	var (
+		mu      sync.Mutex
+		running int
+		max     int
+		done    int
+	)
+	release := make(chan struct{})
+	started := make(chan struct{}, 5)
+	for i := 0; i < 5; i++ {
+		r.Go(func() {
+			mu.Lock()
+			running++
+			if running > max {
+				max = running
+			}
+			mu.Unlock()
+			started <- struct{}{}
+
+			<-release
+			mu.Lock()
+			running--
+			done++
+			mu.Unlock()
+		})
+	}
+
+	// Go does not block while the workers are busy.
+	<-started
+	<-started
+	close(release)
+	r.Wait()
+	require.Equal(t, 2, max)
+	require.Equal(t, 5, done)
+}
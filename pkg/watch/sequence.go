@@ -0,0 +1,101 @@
+package watch
+
+import (
+	"time"
+
+	"github.com/hareku/evdev-trigger/pkg/config"
+)
+
+// sequenceMatcher tracks partial matches of sequences across key presses.
+type sequenceMatcher struct {
+	seqs  []config.SequenceConfig
+	state []sequenceState
+}
+
+// sequenceState is the progress of a sequence.
+type sequenceState struct {
+	// progress is the number of matched keys.
+	progress int
+	// start is the time of the first matched key.
+	start time.Time
+	// presses is the matched presses, the last ones of the input.
+	presses []sequencePress
+}
+
+type sequencePress struct {
+	code uint16
+	t    time.Time
+}
+
+func newSequenceMatcher(seqs []config.SequenceConfig) *sequenceMatcher {
+	return &sequenceMatcher{
+		seqs:  seqs,
+		state: make([]sequenceState, len(seqs)),
+	}
+}
+
+// press advances the sequences by the press of code at t.
+// It returns the completed sequences, and whether any sequence advanced past its first key or completed.
+// On a mismatched key or the timeout, a sequence falls back to the longest tail of the matched presses
+// which is its prefix within the timeout, as in KMP, so that A A B matches A A A B.
+func (m *sequenceMatcher) press(code uint16, t time.Time) (completed []config.SequenceConfig, advanced bool) {
+	for i, seq := range m.seqs {
+		s := &m.state[i]
+		s.presses = longestPrefix(seq, append(s.presses, sequencePress{code: code, t: t}))
+		s.progress = len(s.presses)
+		if s.progress == 0 {
+			continue
+		}
+		s.start = s.presses[0].t
+
+		switch {
+		case s.progress == len(seq.Keys):
+			completed = append(completed, seq)
+			advanced = true
+			s.progress = 0
+			s.presses = nil
+		case s.progress > 1:
+			advanced = true
+		}
+	}
+	return completed, advanced
+}
+
+// longestPrefix returns the longest tail of presses which matches the keys of seq from the first within the timeout.
+// The last press is the one at the time of matching.
+func longestPrefix(seq config.SequenceConfig, presses []sequencePress) []sequencePress {
+	last := presses[len(presses)-1].t
+	for k := len(presses); k > 0; k-- {
+		tail := presses[len(presses)-k:]
+		if k > len(seq.Keys) || last.Sub(tail[0].t) > seq.TimeoutOrDefault() {
+			continue
+		}
+		matched := true
+		for j, p := range tail {
+			if !seq.Keys[j].Has(p.code) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return tail
+		}
+	}
+	return nil
+}
+
+// leaderDeadline reports whether code is the first key of sequences in progress,
+// and returns the time when the last of them times out.
+func (m *sequenceMatcher) leaderDeadline(code uint16) (time.Time, bool) {
+	var deadline time.Time
+	for i, seq := range m.seqs {
+		s := m.state[i]
+		if s.progress != 1 || !seq.Keys[0].Has(code) {
+			continue
+		}
+		if d := s.start.Add(seq.TimeoutOrDefault()); d.After(deadline) {
+			deadline = d
+		}
+	}
+	return deadline, !deadline.IsZero()
+}
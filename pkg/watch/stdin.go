@@ -0,0 +1,85 @@
+package watch
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+)
+
+// EventJSONVersion is the version of the schema of EventJSON.
+// It is incremented on incompatible changes, and fields may be added without it.
+const EventJSONVersion = 1
+
+// EventJSON is the document written to the stdin of commands with stdin: event-json.
+type EventJSON struct {
+	Version int             `json:"version"`
+	Trigger TriggerJSON     `json:"trigger"`
+	Event   *InputEventJSON `json:"event"`
+	Device  DeviceJSON      `json:"device"`
+	Keys    KeyStateJSON    `json:"keys"`
+}
+
+// TriggerJSON is the trigger which ran the command.
+type TriggerJSON struct {
+	Name string `json:"name"`
+	// Mode is the active mode when the trigger fired.
+	Mode string `json:"mode"`
+	// Delta is the delta consumed by a relative trigger, zero for the other triggers.
+	Delta int32 `json:"delta"`
+}
+
+// InputEventJSON is the event which fired the trigger.
+type InputEventJSON struct {
+	Type     string    `json:"type"`
+	Code     uint16    `json:"code"`
+	CodeName string    `json:"code_name"`
+	Value    int32     `json:"value"`
+	Time     time.Time `json:"time"`
+}
+
+// DeviceJSON is the device of the trigger.
+type DeviceJSON struct {
+	Name    string `json:"name"`
+	Phys    string `json:"phys"`
+	Uniq    string `json:"uniq"`
+	Path    string `json:"path"`
+	Vendor  uint16 `json:"vendor"`
+	Product uint16 `json:"product"`
+}
+
+// KeyStateJSON is the state of the keys of the device.
+type KeyStateJSON struct {
+	// Pressed is the names of the keys held when the trigger fired.
+	Pressed []string `json:"pressed"`
+}
+
+// EventJSON returns the document of j written to the stdin of the command.
+func (j Job) EventJSON() ([]byte, error) {
+	doc := EventJSON{
+		Version: EventJSONVersion,
+		Trigger: TriggerJSON{Name: j.Trigger, Mode: j.Mode, Delta: j.Delta},
+		Device: DeviceJSON{
+			Name:    j.Device.Name,
+			Phys:    j.Device.Phys,
+			Uniq:    j.Device.Uniq,
+			Path:    j.Device.Path,
+			Vendor:  j.Device.Vendor,
+			Product: j.Device.Product,
+		},
+		Keys: KeyStateJSON{Pressed: make([]string, 0, len(j.Pressed))},
+	}
+	if ev := j.Event; ev != nil {
+		doc.Event = &InputEventJSON{
+			Type:     evdev.TypeName(ev.Type),
+			Code:     ev.Code,
+			CodeName: evdev.CodeName(ev.Type, ev.Code),
+			Value:    ev.Value,
+			Time:     ev.Timestamp(),
+		}
+	}
+	for _, code := range j.Pressed {
+		doc.Keys.Pressed = append(doc.Keys.Pressed, evdev.CodeName(evdev.EV_KEY, code))
+	}
+	return json.Marshal(doc)
+}
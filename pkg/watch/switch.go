@@ -0,0 +1,81 @@
+package watch
+
+import (
+	"syscall"
+	"time"
+
+	"github.com/hareku/evdev-trigger/pkg/config"
+	"github.com/hareku/evdev-trigger/pkg/evdev"
+)
+
+// matchSwitch updates the state of the switch by ev and returns the switch triggers fired by it.
+func (h *handler) matchSwitch(ev *evdev.InputEvent) []namedTrigger {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	on := ev.Value != 0
+	prev, known := h.switches[ev.Code]
+	h.switches[ev.Code] = on
+	if known && prev == on {
+		return nil
+	}
+	return h.switchTriggers(ev, true)
+}
+
+// loadSwitches reads the states of the switches of the triggers from the connected device,
+// and returns the switch triggers fired by the changes since the device was disconnected.
+// At the first connection, only the triggers with Initial fire.
+func (h *handler) loadSwitches() []namedTrigger {
+	var triggers []namedTrigger
+	read := make(map[uint16]bool)
+	for _, c := range h.bindings.Switches {
+		code := uint16(c.Switch)
+		if read[code] {
+			continue
+		}
+		read[code] = true
+
+		on, err := h.device.SwitchState(code)
+		if err != nil {
+			h.logger.Errorf("%s", err)
+			continue
+		}
+		prev, known := h.switches[code]
+		h.switches[code] = on
+		if known && prev == on {
+			continue
+		}
+		h.logger.Debugf("Switch %s is %t on connection", c.Switch, on)
+		ev := &evdev.InputEvent{
+			Time: syscall.NsecToTimeval(time.Now().UnixNano()),
+			Type: evdev.EV_SW,
+			Code: code,
+		}
+		if on {
+			ev.Value = 1
+		}
+		triggers = append(triggers, h.switchTriggers(ev, known)...)
+	}
+	return triggers
+}
+
+// switchTriggers returns the triggers fired by the switch state of ev.
+// If changed is false, the previous state is unknown and only the triggers with Initial fire.
+func (h *handler) switchTriggers(ev *evdev.InputEvent, changed bool) []namedTrigger {
+	var triggers []namedTrigger
+	on := ev.Value != 0
+	for _, c := range h.bindings.Switches {
+		if uint16(c.Switch) != ev.Code || !c.Fires(on) || (!changed && !c.Initial) {
+			continue
+		}
+		t := newTrigger(switchTriggerName(c), c.CommandConfig, ev)
+		if h.ready(t) {
+			triggers = append(triggers, t)
+		}
+	}
+	return triggers
+}
+
+func switchTriggerName(c config.SwitchConfig) string {
+	return "switch " + c.Name()
+}
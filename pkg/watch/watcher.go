@@ -14,7 +14,7 @@ type Watcher interface {
 }
 
 type NewWatcherInput struct {
-	Phys          string
+	Matcher       evdev.Matcher
 	Logger        Logger
 	Finder        evdev.Finder
 	ReconnectCond *sync.Cond
@@ -23,7 +23,7 @@ type NewWatcherInput struct {
 
 func NewWatcher(in NewWatcherInput) Watcher {
 	return &watcher{
-		phys:    in.Phys,
+		matcher: in.Matcher,
 		logger:  in.Logger,
 		finder:  in.Finder,
 		handler: in.Handler,
@@ -32,7 +32,7 @@ func NewWatcher(in NewWatcherInput) Watcher {
 }
 
 type watcher struct {
-	phys    string
+	matcher evdev.Matcher
 	logger  Logger
 	finder  evdev.Finder
 	cnd     *sync.Cond
@@ -75,22 +75,31 @@ func (w *watcher) waitConnect(ctx context.Context) error {
 
 	ok, err := w.connect(ctx)
 	for !ok {
-		if err != nil && !errors.Is(err, evdev.ErrDeviceNotFound) {
+		// an ambiguous matcher must not stop the other devices, and it is resolved by unplugging a device.
+		switch {
+		case err == nil || errors.Is(err, evdev.ErrDeviceNotFound):
+			w.logger.Debugf("Device not found (%s), waiting device connection.", w.matcher)
+		case errors.Is(err, evdev.ErrAmbiguousDevice):
+			w.logger.Errorf("%s, waiting device disconnection.", err)
+		default:
+			return err
+		}
+		// the owner of ctx broadcasts to cnd after cancellation to stop waiting.
+		if err := ctx.Err(); err != nil {
 			return err
 		}
-		w.logger.Debugf("Device not found (%s), waiting device connection.", w.phys)
 		w.cnd.Wait()
 		ok, err = w.connect(ctx)
 	}
 
-	w.logger.Debugf("Connected to %s", w.phys)
+	w.logger.Debugf("Connected to %s", w.matcher)
 	return nil
 }
 
 func (w *watcher) connect(ctx context.Context) (bool, error) {
-	w.logger.Debugf("Trying to connect %s", w.phys)
+	w.logger.Debugf("Trying to connect %s", w.matcher)
 
-	device, err := w.finder.Find(w.phys)
+	device, err := w.finder.Find(w.matcher)
 	if err != nil {
 		return false, err
 	}
@@ -109,6 +118,17 @@ func (w *watcher) listen(ctx context.Context) error {
 	if w.d == nil {
 		return errDeviceDisconnected
 	}
+	d := w.d
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		if err := d.Close(); err != nil {
+			w.logger.Debugf("Closing device failed: %s", err)
+		}
+		w.d = nil
+		w.handler.Reset()
+	}()
+	w.handler.Connect(ctx, d)
 
 	readCh := make(chan read)
 	go func() {
@@ -118,10 +138,14 @@ func (w *watcher) listen(ctx context.Context) error {
 			case <-ctx.Done():
 				return
 			default:
-				ev, err := w.d.Read()
-				readCh <- read{
+				ev, err := d.Read()
+				select {
+				case readCh <- read{
 					ev:  ev,
 					err: err,
+				}:
+				case <-done:
+					return
 				}
 			}
 		}
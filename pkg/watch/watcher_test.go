@@ -3,7 +3,10 @@ package watch_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -11,6 +14,7 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/hareku/evdev-trigger/pkg/evdev"
 	"github.com/hareku/evdev-trigger/pkg/evdev/evdevmock"
+	"github.com/hareku/evdev-trigger/pkg/notify"
 	"github.com/hareku/evdev-trigger/pkg/watch"
 	"github.com/hareku/evdev-trigger/pkg/watch/watchmock"
 	"github.com/stretchr/testify/require"
@@ -19,7 +23,7 @@ import (
 func Test_watcher_Run_HandleInputEvent(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	ctx := context.Background()
-	phys := "00-00-00-00-00"
+	matcher := evdev.Matcher{Phys: "00-00-00-00-00"}
 
 	handler := watchmock.NewMockHandler(ctrl)
 	handler.EXPECT().Do(gomock.Any(), &evdev.InputEvent{
@@ -38,12 +42,15 @@ func Test_watcher_Run_HandleInputEvent(t *testing.T) {
 		<-ctx.Done()
 		return nil, ctx.Err()
 	})
+	device.EXPECT().Close().Times(1).Return(nil)
+	handler.EXPECT().Connect(gomock.Any(), device).Times(1)
+	handler.EXPECT().Reset().Times(1)
 
 	finder := evdevmock.NewMockFinder(ctrl)
-	finder.EXPECT().Find(phys).Times(1).Return(device, nil)
+	finder.EXPECT().Find(matcher).Times(1).Return(device, nil)
 
 	watcher := watch.NewWatcher(watch.NewWatcherInput{
-		Phys:          phys,
+		Matcher:       matcher,
 		Logger:        watch.NewLogger(io.Discard, true),
 		Finder:        finder,
 		Handler:       handler,
@@ -59,7 +66,7 @@ func Test_watcher_Run_HandleInputEvent(t *testing.T) {
 func Test_watcher_Run_ReconnectDevice(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	ctx := context.Background()
-	phys := "00-00-00-00-00"
+	matcher := evdev.Matcher{Phys: "00-00-00-00-00"}
 	cnd := sync.NewCond(new(sync.Mutex))
 
 	device1 := evdevmock.NewMockDevice(ctrl)
@@ -71,23 +78,34 @@ func Test_watcher_Run_ReconnectDevice(t *testing.T) {
 		}()
 		return nil, errors.New("disconnected")
 	})
+	device1.EXPECT().Close().Times(1).Return(nil)
 	device2 := evdevmock.NewMockDevice(ctrl)
 	device2.EXPECT().Read().Times(1).DoAndReturn(func() (*evdev.InputEvent, error) {
 		<-ctx.Done()
 		return nil, ctx.Err()
 	})
+	device2.EXPECT().Close().Times(1).Return(nil)
 
 	finder := evdevmock.NewMockFinder(ctrl)
 	gomock.InOrder(
-		finder.EXPECT().Find(phys).Times(1).Return(device1, nil),
-		finder.EXPECT().Find(phys).Times(1).Return(device2, nil),
+		finder.EXPECT().Find(matcher).Times(1).Return(device1, nil),
+		finder.EXPECT().Find(matcher).Times(1).Return(device2, nil),
+	)
+
+	// the handler is reset on each disconnection.
+	handler := watchmock.NewMockHandler(ctrl)
+	gomock.InOrder(
+		handler.EXPECT().Connect(gomock.Any(), device1).Times(1),
+		handler.EXPECT().Reset().Times(1),
+		handler.EXPECT().Connect(gomock.Any(), device2).Times(1),
+		handler.EXPECT().Reset().Times(1),
 	)
 
 	watcher := watch.NewWatcher(watch.NewWatcherInput{
-		Phys:          phys,
+		Matcher:       matcher,
 		Logger:        watch.NewLogger(io.Discard, true),
 		Finder:        finder,
-		Handler:       watchmock.NewMockHandler(ctrl),
+		Handler:       handler,
 		ReconnectCond: cnd,
 	})
 
@@ -100,7 +118,62 @@ func Test_watcher_Run_ReconnectDevice(t *testing.T) {
 func Test_watcher_Run_ReconnectDeviceWithNotFoundOnce(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	ctx := context.Background()
-	phys := "00-00-00-00-00"
+	matcher := evdev.Matcher{Phys: "00-00-00-00-00"}
+	cnd := sync.NewCond(new(sync.Mutex))
+
+	device1 := evdevmock.NewMockDevice(ctrl)
+
+	device1.EXPECT().Read().Times(2).DoAndReturn(func() (*evdev.InputEvent, error) {
+		go func() {
+			time.Sleep(time.Millisecond * 200)
+			cnd.Broadcast()
+			time.Sleep(time.Millisecond * 200)
+			cnd.Broadcast()
+		}()
+		return nil, errors.New("disconnected")
+	})
+	device1.EXPECT().Close().Times(1).Return(nil)
+	device2 := evdevmock.NewMockDevice(ctrl)
+	device2.EXPECT().Read().Times(1).DoAndReturn(func() (*evdev.InputEvent, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	device2.EXPECT().Close().Times(1).Return(nil)
+
+	finder := evdevmock.NewMockFinder(ctrl)
+	gomock.InOrder(
+		finder.EXPECT().Find(matcher).Times(1).Return(device1, nil),
+		finder.EXPECT().Find(matcher).Times(1).Return(nil, evdev.ErrDeviceNotFound),
+		finder.EXPECT().Find(matcher).Times(1).Return(device2, nil),
+	)
+
+	// the handler is reset on each disconnection.
+	handler := watchmock.NewMockHandler(ctrl)
+	gomock.InOrder(
+		handler.EXPECT().Connect(gomock.Any(), device1).Times(1),
+		handler.EXPECT().Reset().Times(1),
+		handler.EXPECT().Connect(gomock.Any(), device2).Times(1),
+		handler.EXPECT().Reset().Times(1),
+	)
+
+	watcher := watch.NewWatcher(watch.NewWatcherInput{
+		Matcher:       matcher,
+		Logger:        watch.NewLogger(io.Discard, true),
+		Finder:        finder,
+		Handler:       handler,
+		ReconnectCond: cnd,
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	err := watcher.Run(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func Test_watcher_Run_ReconnectDeviceWithAmbiguousOnce(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx := context.Background()
+	matcher := evdev.Matcher{Phys: "00-00-00-00-00"}
 	cnd := sync.NewCond(new(sync.Mutex))
 
 	device1 := evdevmock.NewMockDevice(ctrl)
@@ -114,24 +187,36 @@ func Test_watcher_Run_ReconnectDeviceWithNotFoundOnce(t *testing.T) {
 		}()
 		return nil, errors.New("disconnected")
 	})
+	device1.EXPECT().Close().Times(1).Return(nil)
 	device2 := evdevmock.NewMockDevice(ctrl)
 	device2.EXPECT().Read().Times(1).DoAndReturn(func() (*evdev.InputEvent, error) {
 		<-ctx.Done()
 		return nil, ctx.Err()
 	})
+	device2.EXPECT().Close().Times(1).Return(nil)
 
+	// the watcher waits until the extra device is unplugged, instead of stopping the other devices.
 	finder := evdevmock.NewMockFinder(ctrl)
 	gomock.InOrder(
-		finder.EXPECT().Find(phys).Times(1).Return(device1, nil),
-		finder.EXPECT().Find(phys).Times(1).Return(nil, evdev.ErrDeviceNotFound),
-		finder.EXPECT().Find(phys).Times(1).Return(device2, nil),
+		finder.EXPECT().Find(matcher).Times(1).Return(device1, nil),
+		finder.EXPECT().Find(matcher).Times(1).Return(nil, fmt.Errorf("%w: 2 devices", evdev.ErrAmbiguousDevice)),
+		finder.EXPECT().Find(matcher).Times(1).Return(device2, nil),
+	)
+
+	// the handler is reset on each disconnection.
+	handler := watchmock.NewMockHandler(ctrl)
+	gomock.InOrder(
+		handler.EXPECT().Connect(gomock.Any(), device1).Times(1),
+		handler.EXPECT().Reset().Times(1),
+		handler.EXPECT().Connect(gomock.Any(), device2).Times(1),
+		handler.EXPECT().Reset().Times(1),
 	)
 
 	watcher := watch.NewWatcher(watch.NewWatcherInput{
-		Phys:          phys,
+		Matcher:       matcher,
 		Logger:        watch.NewLogger(io.Discard, true),
 		Finder:        finder,
-		Handler:       watchmock.NewMockHandler(ctrl),
+		Handler:       handler,
 		ReconnectCond: cnd,
 	})
 
@@ -140,3 +225,64 @@ func Test_watcher_Run_ReconnectDeviceWithNotFoundOnce(t *testing.T) {
 	err := watcher.Run(ctx)
 	require.ErrorIs(t, err, context.DeadlineExceeded)
 }
+
+func Test_watcher_Run_ReconnectDeviceWithAmbiguousOnUnplug(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	matcher := evdev.Matcher{Phys: "00-00-00-00-00"}
+	cnd := sync.NewCond(new(sync.Mutex))
+
+	// the notifier watches the device nodes, and unplugging the extra device removes its node.
+	dir := t.TempDir()
+	extra := filepath.Join(dir, "event1")
+	require.NoError(t, os.WriteFile(extra, nil, 0o600))
+	notified := make(chan error, 1)
+	go func() {
+		notified <- notify.NewFsNotifier(notify.NewFsNotifierInput{Dir: dir}).Subscribe(ctx, cnd)
+	}()
+
+	device1 := evdevmock.NewMockDevice(ctrl)
+	device1.EXPECT().Read().MinTimes(1).Return(nil, errors.New("disconnected"))
+	device1.EXPECT().Close().Times(1).Return(nil)
+	device2 := evdevmock.NewMockDevice(ctrl)
+	device2.EXPECT().Read().Times(1).DoAndReturn(func() (*evdev.InputEvent, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	device2.EXPECT().Close().Times(1).Return(nil)
+
+	finder := evdevmock.NewMockFinder(ctrl)
+	gomock.InOrder(
+		finder.EXPECT().Find(matcher).Times(1).Return(device1, nil),
+		finder.EXPECT().Find(matcher).Times(1).DoAndReturn(func(evdev.Matcher) (evdev.Device, error) {
+			go func() {
+				time.Sleep(time.Millisecond * 200)
+				_ = os.Remove(extra)
+			}()
+			return nil, fmt.Errorf("%w: 2 devices", evdev.ErrAmbiguousDevice)
+		}),
+		finder.EXPECT().Find(matcher).Times(1).Return(device2, nil),
+	)
+
+	handler := watchmock.NewMockHandler(ctrl)
+	gomock.InOrder(
+		handler.EXPECT().Connect(gomock.Any(), device1).Times(1),
+		handler.EXPECT().Reset().Times(1),
+		handler.EXPECT().Connect(gomock.Any(), device2).Times(1),
+		handler.EXPECT().Reset().Times(1),
+	)
+
+	watcher := watch.NewWatcher(watch.NewWatcherInput{
+		Matcher:       matcher,
+		Logger:        watch.NewLogger(io.Discard, true),
+		Finder:        finder,
+		Handler:       handler,
+		ReconnectCond: cnd,
+	})
+	// the notifier starts watching before the device is disconnected.
+	time.Sleep(time.Millisecond * 50)
+	err := watcher.Run(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorIs(t, <-notified, context.DeadlineExceeded)
+}
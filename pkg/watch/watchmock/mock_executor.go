@@ -9,7 +9,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
-	config "github.com/hareku/evdev-trigger/pkg/config"
+	watch "github.com/hareku/evdev-trigger/pkg/watch"
 )
 
 // MockExecutor is a mock of Executor interface.
@@ -36,16 +36,16 @@ func (m *MockExecutor) EXPECT() *MockExecutorMockRecorder {
 }
 
 // Do mocks base method.
-func (m *MockExecutor) Do(ctx context.Context, cmd config.Command) ([]byte, error) {
+func (m *MockExecutor) Do(ctx context.Context, job watch.Job) ([]byte, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Do", ctx, cmd)
+	ret := m.ctrl.Call(m, "Do", ctx, job)
 	ret0, _ := ret[0].([]byte)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Do indicates an expected call of Do.
-func (mr *MockExecutorMockRecorder) Do(ctx, cmd interface{}) *gomock.Call {
+func (mr *MockExecutorMockRecorder) Do(ctx, job interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Do", reflect.TypeOf((*MockExecutor)(nil).Do), ctx, cmd)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Do", reflect.TypeOf((*MockExecutor)(nil).Do), ctx, job)
 }
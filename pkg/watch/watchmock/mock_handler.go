@@ -9,6 +9,7 @@ import (
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
+	config "github.com/hareku/evdev-trigger/pkg/config"
 	evdev "github.com/hareku/evdev-trigger/pkg/evdev"
 )
 
@@ -35,6 +36,18 @@ func (m *MockHandler) EXPECT() *MockHandlerMockRecorder {
 	return m.recorder
 }
 
+// Connect mocks base method.
+func (m *MockHandler) Connect(ctx context.Context, d evdev.Device) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Connect", ctx, d)
+}
+
+// Connect indicates an expected call of Connect.
+func (mr *MockHandlerMockRecorder) Connect(ctx, d interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Connect", reflect.TypeOf((*MockHandler)(nil).Connect), ctx, d)
+}
+
 // Do mocks base method.
 func (m *MockHandler) Do(ctx context.Context, ev *evdev.InputEvent) {
 	m.ctrl.T.Helper()
@@ -46,3 +59,27 @@ func (mr *MockHandlerMockRecorder) Do(ctx, ev interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Do", reflect.TypeOf((*MockHandler)(nil).Do), ctx, ev)
 }
+
+// Reset mocks base method.
+func (m *MockHandler) Reset() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Reset")
+}
+
+// Reset indicates an expected call of Reset.
+func (mr *MockHandlerMockRecorder) Reset() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockHandler)(nil).Reset))
+}
+
+// Update mocks base method.
+func (m *MockHandler) Update(bindings config.Bindings) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Update", bindings)
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockHandlerMockRecorder) Update(bindings interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockHandler)(nil).Update), bindings)
+}